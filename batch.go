@@ -0,0 +1,77 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchOpts are the options of BatchCtx.
+type BatchOpts struct {
+	StopOnFirstError bool // cancel the remaining requests once a request fails
+	MaxConcurrency   int  // maximum number of requests in flight, unlimited if zero or negative
+}
+
+// BatchCtx sends the requests concurrently and waits for all of them to complete, each client instance
+// keeps the result or the Exception of its own request.
+// The requests are sent with a context derived from ctx, which takes precedence over the context of
+// the client instances (see SetContext), so canceling ctx cancels the requests in flight. When
+// opts.StopOnFirstError is set, the first failure cancels the remaining requests, the requests which
+// are not started yet then fail right away with a KindCanceled Exception.
+// The client instances must be distinct, since the calls on a client instance are serialized.
+// It returns the first error reported by Try, a business failure included, or nil if all the requests
+// succeeded.
+//
+// Example usage:
+//
+//	reqs := []*gloria.Client[User]{
+//		gloria.New[User]().SetRequest(gloria.MethodGet, "https://api.example.com/users/1"),
+//		gloria.New[User]().SetRequest(gloria.MethodGet, "https://api.example.com/users/2"),
+//	}
+//	err := gloria.BatchCtx(ctx, reqs, gloria.BatchOpts{StopOnFirstError: true, MaxConcurrency: 4})
+func BatchCtx[T any](ctx context.Context, reqs []*Client[T], opts BatchOpts) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// bounds the requests in flight, nil if unlimited
+	var sem chan struct{}
+	if opts.MaxConcurrency > 0 {
+		sem = make(chan struct{}, opts.MaxConcurrency)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, c := range reqs {
+		if c == nil {
+			continue
+		}
+		if sem != nil {
+			sem <- struct{}{}
+		}
+		wg.Add(1)
+		go func(c *Client[T]) {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+
+			if _, err := c.SendWith(WithRequestContext(ctx)).Try(); err != nil {
+				once.Do(func() {
+					firstErr = err
+					if opts.StopOnFirstError {
+						cancel()
+					}
+				})
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchCtx_MaxConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			m := atomic.LoadInt64(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt64(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"code":0,"data":{"path":"` + r.URL.Path + `"}}`))
+	}))
+	defer srv.Close()
+
+	reqs := make([]*Client[H], 8)
+	for i := range reqs {
+		reqs[i] = New[H]().SetRequest(MethodGet, srv.URL+"/users/"+string(rune('0'+i)))
+	}
+	if err := BatchCtx(context.Background(), reqs, BatchOpts{MaxConcurrency: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("max in flight = %d, want at most 2", maxInFlight)
+	}
+	for i, c := range reqs {
+		if want := "/users/" + string(rune('0'+i)); c.Data()["path"] != want {
+			t.Errorf("request %d data = %v, want path %s", i, c.Data(), want)
+		}
+	}
+}
+
+func TestBatchCtx_StopOnFirstError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	reqs := []*Client[H]{
+		New[H]().SetRequest(MethodGet, srv.URL+"/slow"),
+		New[H]().SetRequest(MethodGet, srv.URL+"/fail"),
+		New[H]().SetRequest(MethodGet, srv.URL+"/slow"),
+	}
+	start := time.Now()
+	err := BatchCtx(context.Background(), reqs, BatchOpts{StopOnFirstError: true})
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("error = %v, want the status error of the failed request", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the remaining requests were not canceled, the batch took %v", elapsed)
+	}
+	for _, i := range []int{0, 2} {
+		if kind := reqs[i].Exception.Kind; kind != KindCanceled {
+			t.Errorf("request %d kind = %q, want %q", i, kind, KindCanceled)
+		}
+	}
+}
+
+func TestBatchCtx_ParentCanceled(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"code":0}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reqs := []*Client[H]{New[H]().SetRequest(MethodGet, srv.URL)}
+	if err := BatchCtx(ctx, reqs, BatchOpts{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+
+	// the context of the batch is not kept by the client instance
+	if err := reqs[0].Reset().Send().Exception; !isEmpty(err) {
+		t.Fatalf("unexpected exception after the batch: %+v", err)
+	}
+}
@@ -4,32 +4,70 @@
 
 package gloria
 
+import (
+	"fmt"
+	"time"
+)
+
 /*
 	A then-catch-finally statement callback style like javascript axios library
 */
 
 type CallbackOk[T any] func(data T)
 type CallbackErr func(e *Exception)
+type CallbackFail func(code int, msg string)
 type CallbackExtra[T any] func(c *Client[T])
 
 // Then sets a callback function to be executed when the HTTP request is successful.
-// The provided callback function cb is invoked only if no exception occurred during the request.
+// The provided callback function cb is invoked only if no exception occurred during the request,
+// and the business code matches one of the success codes (in rest mode).
 // The cb function is called with the result of the request as its argument.
 // After executing the callback function, the client instance is returned.
+//
+// See ThenElse to handle the business failures.
 func (c *Client[T]) Then(cb CallbackOk[T]) *Client[T] {
+	return c.ThenElse(cb, nil)
+}
+
+// ThenElse sets the callback functions to be executed when the HTTP request is successful.
+// The ok callback function is invoked when the business code matches the success code, with the
+// result of the request as its argument, otherwise the bizFail callback function is invoked with
+// the business code and message.
+// Neither callback is invoked if an exception occurred during the request, see Catch.
+// After executing the callback function, the client instance is returned.
+func (c *Client[T]) ThenElse(ok CallbackOk[T], bizFail CallbackFail) *Client[T] {
 	if isEmpty(c.Exception.PanicError) && isEmpty(c.Exception.FailureReason) {
-		// The default is 0, which can be changed by the WithModifySuccessCode(code int) function.
-		if c.Result.Code == c.Config.DefaultOkCode {
+		if c.isBusinessOk() {
 			c.ChalkStr(LogLevelSuccess, "HTTP request successful~ 🎉🎉🎉")
+			ok(c.Result.Data)
 		} else {
 			c.ChalkStr(LogLevelFail, "The HTTP request was successful, but the business failed, please check!")
+			if bizFail != nil {
+				bizFail(c.Result.Code, c.Result.Msg)
+			}
 		}
-		cb(c.Result.Data)
 	}
 
 	return c
 }
 
+// isBusinessOk reports whether the business code of the response matches one of the success codes.
+// The default is 0, which can be changed by the DefineOkCode method or the WithOkCodes option. There
+// is no business code in http mode, nor in a response without a body (such as a 204 or a 304
+// response), so they are always considered successful.
+func (c *Client[T]) isBusinessOk() bool {
+	if !c.Config.IsRestMode {
+		return true
+	}
+	if c.Context.Response.length == 0 && (c.allowEmptyBody() || c.NotModified()) {
+		return true
+	}
+	if c.Config.SuccessFunc != nil {
+		return c.Config.SuccessFunc(c.Context.Response.bs)
+	}
+	return isOkCode(c.Result.Code, c.Config.DefaultOkCode, c.Config.OkCodes)
+}
+
 // Catch sets a callback function to be executed when an exception occurs during the HTTP request.
 // The provided callback function cb is invoked only if an exception exists in the client instance.
 // The cb function is called with the exception object as its argument.
@@ -60,3 +98,37 @@ func (c *Client[T]) Finally(cb CallbackExtra[T], printLog ...bool) {
 	}
 	cb(c)
 }
+
+// SafeChain runs the chain callback function with the client instance, and always runs the finally
+// callback function afterward, even if the chain panics (such as Unwrap on a failed request), like
+// the finally statement of javascript.
+// A recovered panic is recorded as the Exception of the client instance if none is recorded yet, so
+// that it can still be handled (such as by Catch) after the chain.
+//
+// Example usage:
+//
+//	client.SafeChain(func(c *Client[T]) {
+//		c.Send().Unwrap()
+//	}, func(c *Client[T]) {
+//		fmt.Println("always executed")
+//	})
+func (c *Client[T]) SafeChain(chain CallbackExtra[T], finally CallbackExtra[T]) (client *Client[T]) {
+	defer func() {
+		client = c
+		if r := recover(); r != nil {
+			if isEmpty(c.Exception.PanicError) {
+				c.Exception = &Exception{
+					CodeLocation:   fileLocation(3),
+					PanicError:     fmt.Errorf("recovered panic: %v", r),
+					OccurrenceTime: time.Now().Unix(),
+				}
+			}
+			c.ChalkPrintf(LogLevelPanic, "Recovered from panic in chain: %v", r)
+		}
+		finally(c)
+	}()
+
+	chain(c)
+
+	return
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestThenElse(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"code":1001,"msg":"invalid name","data":null}`)
+
+	var okCalled bool
+	var gotCode int
+	var gotMsg string
+	New[H]().SetRequest(MethodPost, srv.URL+"/users").Send().
+		Then(func(data H) {
+			okCalled = true
+		}).
+		ThenElse(func(data H) {
+			okCalled = true
+		}, func(code int, msg string) {
+			gotCode, gotMsg = code, msg
+		})
+
+	if okCalled {
+		t.Error("the success callback should not run on a business failure")
+	}
+	if gotCode != 1001 || gotMsg != "invalid name" {
+		t.Errorf("business failure = %d %q", gotCode, gotMsg)
+	}
+}
+
+func TestThen_OkCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		client func() *Client[H]
+		wantOk bool
+	}{
+		{"option first code", `{"code":0,"data":{}}`, func() *Client[H] { return New[H]().Optional(WithOkCodes[H](0, 200)) }, true},
+		{"option second code", `{"code":200,"data":{}}`, func() *Client[H] { return New[H]().Optional(WithOkCodes[H](0, 200)) }, true},
+		{"option other code", `{"code":1001,"data":{}}`, func() *Client[H] { return New[H]().Optional(WithOkCodes[H](0, 200)) }, false},
+		{"define codes", `{"code":20000,"data":{}}`, func() *Client[H] { return New[H]().DefineOkCode(0, 20000) }, true},
+		{"define replaces codes", `{"code":200,"data":{}}`, func() *Client[H] { return New[H]().DefineOkCode(0, 200).DefineOkCode(0) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, http.StatusOK, tt.body)
+
+			var okCalled bool
+			tt.client().SetRequest(MethodGet, srv.URL).Send().Then(func(data H) {
+				okCalled = true
+			})
+			if okCalled != tt.wantOk {
+				t.Errorf("success callback called = %v, want %v", okCalled, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestThen_EmptyBody(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+		srv := newTestServer(t, status, ``)
+
+		var okCalled bool
+		c := New[H]().DefineOkCode(200).SetRequest(MethodDelete, srv.URL).Send().Then(func(data H) {
+			okCalled = true
+		})
+		if _, err := c.Try(); err != nil || !c.IsOk() || !okCalled {
+			t.Errorf("status %d: Try() = %v, IsOk() = %v, success callback called = %v, want a success",
+				status, err, c.IsOk(), okCalled)
+		}
+	}
+}
+
+func TestSafeChain(t *testing.T) {
+	var finallyCalled bool
+	var caught *Exception
+
+	// Nothing listens on port 1, so Unwrap panics on the network error.
+	New[H]().SafeChain(func(c *Client[H]) {
+		c.SetRequest(MethodGet, "http://127.0.0.1:1/ping").Send().Unwrap()
+	}, func(c *Client[H]) {
+		finallyCalled = true
+	}).Catch(func(e *Exception) {
+		caught = e
+	})
+
+	if !finallyCalled {
+		t.Error("the finally callback should run after a panic")
+	}
+	if caught == nil || caught.PanicError == nil {
+		t.Error("the exception should be kept after the chain")
+	}
+}
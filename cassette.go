@@ -0,0 +1,202 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// RecordMode defines whether a Cassette records the interactions or replays them.
+type RecordMode int
+
+// Record modes
+const (
+	// ModeReplay serves the recorded responses without network, an unrecorded request fails
+	ModeReplay RecordMode = iota
+
+	// ModeRecord sends the requests and saves each interaction to the cassette file, the interactions of
+	// a previous recording are discarded
+	ModeRecord
+)
+
+// Cassette records the request and response pairs of a client instance to a file, and replays them
+// without network, see WithCassette. A request is matched by its method, its url and the hash of its
+// body. The response bodies are stored in base64, so that binary bodies are replayed unchanged.
+type Cassette struct {
+	path string
+	mode RecordMode
+
+	mu           sync.Mutex
+	loaded       bool
+	interactions []cassetteInteraction
+	replayed     map[string]int // number of replayed interactions per key
+}
+
+// cassetteInteraction is a request and response pair of a Cassette file.
+type cassetteInteraction struct {
+	Key      string      `json:"key"`
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	Protocol string      `json:"protocol"`
+}
+
+// NewCassette creates a Cassette backed by the file at path, which is read lazily.
+func NewCassette(path string, mode RecordMode) *Cassette {
+	return &Cassette{
+		path:     path,
+		mode:     mode,
+		replayed: map[string]int{},
+	}
+}
+
+// transport returns the RoundTripper of the cassette, which sends the recorded requests through next.
+func (cs *Cassette) transport(next http.RoundTripper) http.RoundTripper {
+	return &cassetteTransport{cassette: cs, next: next}
+}
+
+// load reads the interactions of the cassette file once, a missing file is an empty cassette.
+// A recording cassette starts empty and overwrites the file. The caller must hold the mutex.
+func (cs *Cassette) load() error {
+	if cs.loaded {
+		return nil
+	}
+	if cs.mode == ModeRecord {
+		cs.loaded = true
+		return nil
+	}
+
+	data, err := os.ReadFile(cs.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &cs.interactions); err != nil {
+			return fmt.Errorf("cassette %s: %w", cs.path, err)
+		}
+	}
+	cs.loaded = true
+
+	return nil
+}
+
+// save writes the interactions to the cassette file. The caller must hold the mutex.
+func (cs *Cassette) save() error {
+	data, err := json.MarshalIndent(cs.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(cs.path, data, 0o644)
+}
+
+// cassetteTransport is the http.RoundTripper of a Cassette.
+type cassetteTransport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+	key := cassetteKey(req, reqBody)
+
+	cs := t.cassette
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if err := cs.load(); err != nil {
+		return nil, err
+	}
+
+	if cs.mode == ModeReplay {
+		return cs.replay(req, key)
+	}
+
+	// Send the request with the consumed body restored
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(reqBody))
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	cs.interactions = append(cs.interactions, cassetteInteraction{
+		Key:      key,
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Status:   resp.StatusCode,
+		Header:   resp.Header,
+		Body:     respBody,
+		Protocol: resp.Proto,
+	})
+	if err = cs.save(); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// replay serves the recorded response of the request. Identical requests are served their recorded
+// responses in order, the last one being repeated. The caller must hold the mutex.
+func (cs *Cassette) replay(req *http.Request, key string) (*http.Response, error) {
+	var matches []cassetteInteraction
+	for _, it := range cs.interactions {
+		if it.Key == key {
+			matches = append(matches, it)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("cassette %s: no interaction recorded for %s %s", cs.path, req.Method, req.URL)
+	}
+
+	idx := cs.replayed[key]
+	if idx >= len(matches) {
+		idx = len(matches) - 1
+	}
+	cs.replayed[key]++
+	it := matches[idx]
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", it.Status, http.StatusText(it.Status)),
+		StatusCode:    it.Status,
+		Proto:         it.Protocol,
+		Header:        it.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(it.Body)),
+		ContentLength: int64(len(it.Body)),
+		Request:       req,
+	}, nil
+}
+
+// cassetteKey returns the key matching a request, made of its method, its url and the hash of its body.
+func cassetteKey(req *http.Request, body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf("%s %s %s", req.Method, req.URL.String(), hex.EncodeToString(sum[:8]))
+}
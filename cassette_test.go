@@ -0,0 +1,86 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithCassette(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set(HeaderContentTypeKey, JsonContentType)
+		_, _ = w.Write([]byte(`{"code":0,"msg":` + string(b) + `}`))
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	send := func(mode RecordMode, payload string) *Client[H] {
+		return New[H](WithCassette[H](path, mode)).SetRequest(MethodPost, srv.URL+"/echo").SetPayload(payload).Send()
+	}
+
+	// A stale interaction of a previous recording is discarded
+	if c := send(ModeRecord, "stale"); !isEmpty(c.Exception) {
+		t.Fatalf("record: unexpected exception: %+v", c.Exception)
+	}
+	rec := New[H](WithCassette[H](path, ModeRecord))
+	for _, payload := range []string{"a", "b"} {
+		if rec.SetRequest(MethodPost, srv.URL+"/echo").SetPayload(payload).Send(); !isEmpty(rec.Exception) {
+			t.Fatalf("record: unexpected exception: %+v", rec.Exception)
+		}
+	}
+	srv.Close()
+
+	for _, payload := range []string{"b", "a"} {
+		c := send(ModeReplay, payload)
+		if !isEmpty(c.Exception) || c.Result.Msg != payload {
+			t.Errorf("replay %s: msg = %q, exception %+v", payload, c.Result.Msg, c.Exception)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+
+	if c := send(ModeReplay, "stale"); c.Exception.Kind != KindNetwork {
+		t.Errorf("exception = %+v, want the stale interaction discarded", c.Exception)
+	}
+	if c := send(ModeReplay, "c"); c.Exception.Kind != KindNetwork {
+		t.Errorf("exception = %+v, want an unrecorded request error", c.Exception)
+	}
+}
+
+func TestCassette_BinaryBody(t *testing.T) {
+	body := []byte{0x00, 0xff, 0xfe, 0x80, 'g', 0xc3, 0x28}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContentTypeKey, "application/octet-stream")
+		_, _ = w.Write(body)
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	get := func(mode RecordMode) ([]byte, error) {
+		client := &http.Client{Transport: NewCassette(path, mode).transport(http.DefaultTransport)}
+		resp, err := client.Get(srv.URL + "/blob")
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return io.ReadAll(resp.Body)
+	}
+
+	if _, err := get(ModeRecord); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	srv.Close()
+
+	if got, err := get(ModeReplay); err != nil || !bytes.Equal(got, body) {
+		t.Errorf("replay: body = %x, %v, want %x", got, err, body)
+	}
+}
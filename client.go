@@ -5,13 +5,18 @@
 package gloria
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -60,6 +65,12 @@ const (
 
 	// Horizontal line character "-"
 	signHorizontal = "-"
+
+	// Colon character ":"
+	signColon = ":"
+
+	// Dot character "."
+	signDot = "."
 )
 
 const (
@@ -116,6 +127,20 @@ const (
 	FormContentType = "application/x-www-form-urlencoded"
 )
 
+const (
+	// CompressionGzip gzip request body compression
+	CompressionGzip = "gzip"
+
+	// CompressionDeflate deflate request body compression
+	CompressionDeflate = "deflate"
+
+	// defaultCompressThreshold Request bodies smaller than it are not compressed (1 KB)
+	defaultCompressThreshold = 1 << 10
+
+	// maxRedirects Default maximum number of redirects followed by a request
+	maxRedirects = 10
+)
+
 var (
 	// QueryMethods Method list in a String Slice
 	QueryMethods = []string{
@@ -138,6 +163,12 @@ var (
 	HeaderContentLanguageKey = http.CanonicalHeaderKey("Content-Language")
 	HeaderContentEncodingKey = http.CanonicalHeaderKey("Content-Encoding")
 	HeaderAuthorizationKey   = http.CanonicalHeaderKey("Authorization")
+	HeaderAllowKey           = http.CanonicalHeaderKey("Allow")
+	HeaderAllowMethodsKey    = http.CanonicalHeaderKey("Access-Control-Allow-Methods")
+	HeaderIdempotencyKey     = http.CanonicalHeaderKey("Idempotency-Key")
+	HeaderRequestIDKey       = http.CanonicalHeaderKey("X-Request-ID")
+	HeaderETagKey            = http.CanonicalHeaderKey("ETag")
+	HeaderIfNoneMatchKey     = http.CanonicalHeaderKey("If-None-Match")
 )
 
 type Client[T any] struct {
@@ -153,19 +184,51 @@ type Client[T any] struct {
 	// intercepted error
 	Exception *Exception
 
+	// error of the request builders (such as an invalid host), see failBuild
+	buildErr *Exception
+
+	// options registered by RegisterHost, nil unless the client is created by Default
+	hostDefaults *hostDefaults
+
 	// expandable body
 	Result *RESTFulResp[T]
 
 	// middlewares
-	beforeRequest []func(*Client[T]) error
-	afterResponse []func(*Client[T]) error
+	beforeRequest []hook[T]
+	afterResponse []hook[T]
+	transforms    []func([]byte) ([]byte, error)
+	store         H // values shared by the hooks, see Set
+
+	// guards Send against concurrent calls
+	mu sync.Mutex
 
 	// request content
 	urls          *urls
 	params        SMap
+	pathParams    SMap
+	rawQuery      string
+	apiVersion    string
+	idempotency   string
+	autoIdemKey   bool // generate the idempotency key for every Send, see SetAutoIdempotencyKey
+	idemKeySent   bool // the idempotency key was sent by a previous Send
+	requestID     string
+	retries       int
 	authorization *authorization
 	headers       *header
 	payload       any
+	hasPayload    bool
+	expectType    string
+	graphql       bool
+	bodyReader    io.Reader
+	bodyLength    int64
+	bodyRead      bool // the body reader was consumed by a previous request
+	progress      func(sent, total int64)
+	template      *requestTemplate
+	ctx           context.Context // the context of the request, see SetContext
+	prebuilt      *http.Request   // the request of the ongoing Do call
+
+	// options of the ongoing SendWith call
+	sendOpts *sendOptions
 }
 
 // H is a type alias for an exported map[string]interface{}
@@ -209,6 +272,20 @@ type header struct {
 	extra       SMap
 }
 
+// setDefault sets the field of the header named key to value, unless the field is already set or the
+// header is set through SetHeader (the name is case-insensitive).
+func (h *header) setDefault(field *string, key, value string) {
+	if !isEmpty(*field) {
+		return
+	}
+	for k := range h.extra {
+		if http.CanonicalHeaderKey(k) == key {
+			return
+		}
+	}
+	*field = value
+}
+
 type Context struct {
 	// client object
 	HttpClient *http.Client
@@ -225,24 +302,123 @@ type Meta struct {
 	Url        string        // store the full url path
 	Duration   time.Duration // time-consuming current request
 	ReceivedAt time.Time     // store the timestamp indicating when the response was received
+	Redirects  []RedirectHop // store the redirects followed by the request, in order
+
+	// phase durations of the last attempt, recorded when the client trace is enabled, see WithClientTrace
+	DNSTime     time.Duration // dns lookup
+	ConnectTime time.Duration // tcp connection
+	TLSTime     time.Duration // tls handshake
+	TTFB        time.Duration // time to first byte of the response, from the start of the attempt
+
+	ConnReused bool // store whether the connection of the last attempt was reused (keep-alive)
+}
+
+// RedirectHop is a redirect followed by a request, see RedirectHistory.
+type RedirectHop struct {
+	StatusCode int    // http status code of the redirect response, such as 302
+	URL        string // url of the request which was redirected
+	Location   string // url the request was redirected to
 }
 
 type Config struct {
-	Timeout       time.Duration
-	SkipTLS       bool
-	FilterSlash   bool
-	IsDebug       bool
-	Logger        *log.Logger
-	IsRestMode    bool
-	DefaultOkCode int
-	JSONLoader    JSONLibrary
+	Timeout               time.Duration // timeout of each attempt
+	DialTimeout           time.Duration // timeout of establishing a connection, including dns
+	TLSHandshakeTimeout   time.Duration // timeout of the tls handshake
+	ResponseHeaderTimeout time.Duration // timeout of waiting for the response headers once the request is written
+	TotalTimeout          time.Duration // deadline over all attempts, including retries
+	RetryCount            int           // number of retries on network errors and retryable statuses
+	RetryWait             time.Duration // wait duration between the attempts
+	SkipTLS               bool
+	FilterSlash           bool
+	BaseURL               string // the base URL which relative request paths are resolved against
+	StrictURL             bool   // panic on invalid scheme or host instead of recording an exception
+	IsDebug               bool
+	Logger                *log.Logger
+	Color                 bool          // colorize the log levels with ANSI escape codes
+	SlowThreshold         time.Duration // requests slower than it are logged at the WARN level
+	IsRestMode            bool
+	DefaultOkCode         int
+	OkCodes               []int // business success codes in rest mode, only DefaultOkCode if empty
+	AcceptStatuses        []int // accepted http status codes, all 2xx by default
+	JSONLoader            JSONLibrary
+	EnvelopeKeys          EnvelopeKeys          // json keys of the rest mode envelope
+	SuccessFunc           func(raw []byte) bool // business success determination in rest mode
+	DataPath              string                // dot-path of the data subtree, such as "data.items.0"
+	KeyCase               KeyCase               // rewrite of the response keys before decoding, none by default
+	Stats                 *Stats                // collector of the request durations, optional
+	MetricsHook           func(RequestMetrics)  // called once per Send with the metrics of the request, optional
+	Compression           string                // content encoding of the request body, such as "gzip"
+	CompressAbove         int                   // minimum size in bytes of a compressed request body
+	RequestIDHeader       string                // header name of the generated request id, disabled if empty
+	CertPins              []string              // sha-256 fingerprints of the accepted server certificates
+	HTTP2                 bool                  // force HTTP/2, disabling HTTP/1.1
+	H2C                   bool                  // use cleartext HTTP/2 for http:// URLs
+	DefaultHeaders        SMap                  // standing headers of every request, overridden by the request headers
+	Transport             http.RoundTripper     // custom transport replacing the default one, such as a MockTransport
+	Cassette              *Cassette             // records or replays the requests, optional
+	OmitEmpty             bool                  // omit the empty fields of the marshaled payload
+	OmitNull              bool                  // omit the null fields of the marshaled payload
+	ErrorsKey             string                // dot-path of the errors array of the response body, optional
+	RawCommaParams        bool                  // keep the commas of the query parameters literal
+	Proxy                 string                // url of the proxy, optional
+	RedactHeaders         []string              // headers masked when printed, besides Authorization, cookies and X-Api-Key
+	UseNumber             bool                  // decode the numbers of the interface{} values into json.Number
+	ClientTrace           bool                  // record the phase durations of the requests into Meta
+	MaxRedirects          int                   // maximum number of redirects followed, 10 if zero, none if negative
+	AllowGetBody          bool                  // send the payload of the GET requests of the shorthands
+	JSONPrefix            string                // prefix of the lines of the indented payload, see WithIndentedJSON
+	JSONIndent            string                // indentation of the indented payload, compact if both are empty
+	APIVersion            string                // version segment of the request paths, such as "v1", optional
+
+	// default transport reused by the requests, see defaultTransport
+	transport    *http.Transport
+	transportKey transportKey
 }
 
 type Exception struct {
 	CodeLocation   string
+	Kind           ErrorKind
 	PanicError     error
 	FailureReason  string
+	StatusError    *StatusError
 	OccurrenceTime timestamp
+
+	// errors array of the response body, see Errors
+	errors []APIError
+}
+
+// Errors returns the structured errors of the response body, located by Config.ErrorsKey (or the
+// errors array of a GraphQL response), such as the validation errors of a batch request. It returns
+// nil if the body has no errors array.
+func (e *Exception) Errors() []APIError {
+	return e.errors
+}
+
+// Error returns the message of the recorded error, or the failure reason, or the message of the
+// StatusError, so that an Exception is an error (see Try). It returns an empty string if nothing is
+// recorded.
+func (e *Exception) Error() string {
+	switch {
+	case e.PanicError != nil:
+		return e.PanicError.Error()
+	case !isEmpty(e.FailureReason):
+		return e.FailureReason
+	case e.StatusError != nil:
+		return e.StatusError.Error()
+	}
+	return ""
+}
+
+// Unwrap returns the recorded error, or the StatusError of an unexpected http status, so that errors.Is
+// and errors.As inspect the cause of the Exception, such as errors.Is(err, context.DeadlineExceeded).
+func (e *Exception) Unwrap() error {
+	if e.PanicError != nil {
+		return e.PanicError
+	}
+	if e.StatusError != nil {
+		return e.StatusError
+	}
+	return nil
 }
 
 type RESTFulResp[T any] struct {
@@ -260,125 +436,578 @@ type Response struct {
 	length int64
 }
 
-func (c *Client[T]) Send() *Client[T] {
-	// request middleware
-	for _, md := range c.beforeRequest {
-		if err := md(c); err != nil {
-			c.Exception = &Exception{
-				CodeLocation:   fileLocation(1),
-				PanicError:     err,
-				OccurrenceTime: time.Now().Unix(),
-			}
-			return c
+// SendOption configures a single call of SendWith, without mutating the client instance.
+type SendOption func(o *sendOptions)
+
+// sendOptions holds the settings of a single call of SendWith.
+type sendOptions struct {
+	headers SMap            // transient request headers
+	ctx     context.Context // context of the request, see WithRequestContext
+}
+
+// WithRequestHeaders is a SendOption function that sets headers for a single request only, such as
+// X-Request-ID. They override the headers of the client instance, which are left unchanged.
+func WithRequestHeaders(h H) SendOption {
+	return func(o *sendOptions) {
+		for k, v := range h {
+			o.headers[http.CanonicalHeaderKey(k)] = fmt.Sprint(v)
 		}
 	}
+}
 
-	// create
-	c.createRequest()
+// WithRequestContext is a SendOption function that sets the context of a single request only, it
+// takes precedence over the context of the client instance (see SetContext), which is left unchanged.
+func WithRequestContext(ctx context.Context) SendOption {
+	return func(o *sendOptions) {
+		o.ctx = ctx
+	}
+}
 
-	// record start time
-	startTime := time.Now()
+// Send sends the request of the client instance and decodes the response.
+// Concurrent calls on the same client instance are serialized, each one waits for the previous
+// one to complete, since they share the request and response state.
+func (c *Client[T]) Send() *Client[T] {
+	return c.SendWith()
+}
 
-	// execute
-	resp, err := c.Context.HttpClient.Do(c.Context.Request)
+// SendWith is like Send, with options scoped to this single request, such as WithRequestHeaders.
+// The client instance is not mutated by the options, so a reused client keeps its base configuration
+// (such as the authorization and the User-Agent) while varying per-call settings.
+//
+// Example usage:
+//
+//	c.SendWith(gloria.WithRequestHeaders(gloria.H{"X-Request-ID": id}))
+func (c *Client[T]) SendWith(opts ...SendOption) *Client[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sendOpts = &sendOptions{headers: SMap{}}
+	for _, opt := range opts {
+		opt(c.sendOpts)
+	}
+	defer func() { c.sendOpts = nil }()
 
-	if err != nil {
+	return c.send()
+}
+
+// Do is like Send, with a request built by the caller instead of the builders of the client instance,
+// as an escape hatch for the requests they cannot express.
+// The url, the query and path parameters, the headers, the cookies, the authorization, the payload and
+// the request middleware (see UsePreHooks) of the client instance are not applied, while the http
+// client settings (such as the timeouts, the retries and the redirects), the response middleware, the
+// logging and the decoding of the response are, so that Echo, Try and the callbacks work as usual.
+// The request is sent with its own context, the one of SetContext is not applied.
+// The method and the url of the request are recorded in Meta.
+//
+// Example usage:
+//
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, "https://api.example.com/files/1", f)
+//	req.Header.Set("Content-Type", "application/octet-stream")
+//	client.Do(req).Catch(func(e *gloria.Exception) { ... })
+func (c *Client[T]) Do(req *http.Request) *Client[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req == nil || req.URL == nil {
 		c.Exception = &Exception{
 			CodeLocation:   fileLocation(1),
-			PanicError:     err,
+			Kind:           KindRequest,
+			PanicError:     errors.New("nil request or request url"),
 			OccurrenceTime: time.Now().Unix(),
 		}
 		return c
 	}
 
+	c.prebuilt = req
+	defer func() { c.prebuilt = nil }()
+
+	return c.send()
+}
+
+// send sends the request and decodes the response, the caller holds the lock of the client instance.
+func (c *Client[T]) send() *Client[T] {
+	// the metrics hook fires once the request completes, after the deferred close of the body
+	c.retries = 0
+	defer c.reportMetrics(time.Now())
+
+	resp, cancel := c.roundTrip()
+	if resp == nil {
+		return c
+	}
+	defer cancel()
+
 	defer func() {
-		if err = resp.Body.Close(); err != nil {
+		if err := resp.Body.Close(); err != nil {
 			// Handle Close() errors, such as logging or returning an error message
 			c.Exception = &Exception{
 				CodeLocation:   fileLocation(1),
+				Kind:           KindNetwork,
 				PanicError:     err,
 				OccurrenceTime: time.Now().Unix(),
 			}
-			c.ChalkPrintf(LogLevelPanic, "Failed to close response body:", err)
+			c.ChalkPrintf(LogLevelPanic, "Failed to close response body: %v", err)
 		}
 	}()
 
-	// record end time
-	duration := time.Since(startTime)
-	c.Meta.Duration = duration
+	// a HEAD response has no body, only the status and headers are kept
+	var (
+		body []byte
+		err  error
+	)
+	if c.Meta.Method != MethodHead {
+		body, err = ioutil.ReadAll(resp.Body)
+		if err != nil {
+			c.Exception = &Exception{
+				CodeLocation:   fileLocation(1),
+				Kind:           classifyError(err),
+				PanicError:     err,
+				OccurrenceTime: time.Now().Unix(),
+			}
+			return c
+		}
+	}
 
-	// record received At
-	c.Meta.ReceivedAt = time.Now()
+	c.Context.Response = &Response{
+		R:      resp,
+		Status: resp.StatusCode,
+		bs:     body,
+		text:   string(body),
+		length: int64(len(body)), // the real byte count, resp.ContentLength is -1 for chunked bodies
+	}
 
-	// response middleware
-	for _, md := range c.afterResponse {
-		if err = md(c); err != nil {
+	// A 304 Not Modified response to a conditional request has no body, the cached data is still valid
+	if c.NotModified() {
+		return c
+	}
+
+	// The body transforms (such as a decryption) run before the decoding, see UseBodyTransform
+	if len(body) > 0 && len(c.transforms) > 0 {
+		if body, err = c.transformBody(body); err != nil {
+			if !c.IsSuccessStatus() {
+				c.recordUndecodableStatus()
+				return c
+			}
 			c.Exception = &Exception{
 				CodeLocation:   fileLocation(1),
+				Kind:           KindDecode,
 				PanicError:     err,
 				OccurrenceTime: time.Now().Unix(),
 			}
 			return c
 		}
+		c.Context.Response.bs, c.Context.Response.text = body, string(body)
+		c.Context.Response.length = int64(len(body))
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	emptyBody := c.Context.Response.length == 0
+
+	// A response of another Content-Type (such as the html login page of a proxy) is not decoded
+	if !emptyBody && c.IsSuccessStatus() && !matchContentType(resp.Header.Get(HeaderContentTypeKey), c.expectType) {
+		c.Exception = &Exception{
+			CodeLocation: fileLocation(1),
+			Kind:         KindDecode,
+			PanicError: fmt.Errorf("%w %q, want %q: %s", ErrUnexpectedContentType,
+				resp.Header.Get(HeaderContentTypeKey), c.expectType, bodySnippet(body, bodySnippetSize)),
+			OccurrenceTime: time.Now().Unix(),
+		}
+		return c
+	}
+	if emptyBody && !c.allowEmptyBody() {
+		if !c.IsSuccessStatus() {
+			c.recordUndecodableStatus()
+			return c
+		}
 		c.Exception = &Exception{
 			CodeLocation:   fileLocation(1),
-			PanicError:     err,
+			Kind:           KindDecode,
+			PanicError:     errors.New("response body length is 0"),
 			OccurrenceTime: time.Now().Unix(),
 		}
 		return c
 	}
 
-	c.Context.Response = &Response{
-		R:      resp,
-		Status: resp.StatusCode,
-		bs:     body,
-		text:   string(body),
-		length: resp.ContentLength,
+	// An allowed empty body skips the decoding, so Data returns the zero value of T
+	if !emptyBody {
+		if errJson := c.decodeBody(c.Context.Response.bs); errJson != nil {
+			// An error page (such as the html 502 page of a gateway) is reported by its http status
+			if !c.IsSuccessStatus() {
+				c.recordUndecodableStatus()
+				return c
+			}
+			c.Exception = &Exception{
+				CodeLocation:   fileLocation(1),
+				Kind:           KindDecode,
+				PanicError:     errJson,
+				OccurrenceTime: time.Now().Unix(),
+			}
+			return c
+		}
 	}
 
-	if c.Context.Response.length == 0 {
+	if c.Config.IsDebug {
+		// the sensitive headers are masked, see WithRedactHeaders
+		c.ChalkPrintf(LogLevelDebug, "Request headers: %s", formatHeaders(redactHeaders(c.Context.Request.Header, c.Config.RedactHeaders)))
+		c.ChalkPrintf(LogLevelDebug, "Response headers: %s", formatHeaders(redactHeaders(resp.Header, c.Config.RedactHeaders)))
+		c.ChalkStr(LogLevelDebug, c.Context.Response.text)
+	}
+
+	// A structured errors array of the body (see WithErrorsKey) details the failure
+	apiErrs := c.apiErrors(c.Context.Response.bs)
+
+	if !c.IsSuccessStatus() {
+		statusErr := newStatusError(c.Context.Response)
+		kind := KindStatus
+		reason := c.Result.Msg
+		if !c.Config.IsRestMode {
+			// There is no business message in http mode, use the http status instead
+			reason = statusErr.Error()
+		} else if !c.isBusinessOk() {
+			// The server explains the failure with a business code
+			kind = KindBusiness
+		}
 		c.Exception = &Exception{
 			CodeLocation:   fileLocation(1),
-			PanicError:     errors.New("response body length is 0"),
+			Kind:           kind,
+			FailureReason:  reason,
+			StatusError:    statusErr,
 			OccurrenceTime: time.Now().Unix(),
+			errors:         apiErrs,
 		}
 		return c
 	}
 
-	var errJson error
-	if c.Config.IsRestMode {
-		errJson = c.Config.JSONLoader.Unmarshal(c.Context.Response.bs, &c.Result)
-	} else {
-		errJson = c.Config.JSONLoader.Unmarshal(c.Context.Response.bs, &c.Result.Data)
-	}
-	if errJson != nil {
+	// An errors array in a successful response (such as the one of GraphQL) is a business failure
+	if len(apiErrs) > 0 {
 		c.Exception = &Exception{
 			CodeLocation:   fileLocation(1),
-			PanicError:     errJson,
+			Kind:           KindBusiness,
+			FailureReason:  joinAPIErrors(apiErrs),
 			OccurrenceTime: time.Now().Unix(),
+			errors:         apiErrs,
 		}
-		return c
 	}
 
-	if c.Config.IsDebug {
-		c.ChalkStr(LogLevelDebug, c.Context.Response.text)
+	return c
+}
+
+// BuildRequest runs the request middleware and creates the request without sending it, as a dry run,
+// it returns the request with the url, the headers, the authorization and the cookies of the client
+// instance applied, that is the request Send would actually send. It is meant for debugging (see
+// ToCurl), and for the integrations which send the request themselves, such as a websocket handshake,
+// then Context.HttpClient is the configured http client. A failure is recorded in the Exception and
+// returned as the error of Try.
+func (c *Client[T]) BuildRequest() (*http.Request, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.prepare() {
+		_, err := c.Try()
+		return nil, err
 	}
+	return c.Context.Request, nil
+}
 
-	if c.Context.Response.Status != http.StatusOK {
+// prepare runs the request middleware and creates the request, it reports whether the request is ready,
+// otherwise an Exception is recorded.
+func (c *Client[T]) prepare() bool {
+	// an exception recorded while building the request (such as an invalid host) short-circuits,
+	// while the one of a previous send is discarded
+	if c.buildErr != nil {
+		c.Exception = c.buildErr
+		return false
+	}
+	c.Exception = &Exception{}
+
+	// a prebuilt request (see Do) bypasses the request middleware and the builders
+	if c.prebuilt != nil {
+		c.Context.Request = c.prebuilt
+		c.Meta.Method, c.Meta.Url = c.prebuilt.Method, c.prebuilt.URL.String()
+		if isEmptyString(c.Meta.Method) {
+			c.Meta.Method = MethodGet
+		}
+		c.requestID = ""
+		if !isEmptyString(c.Config.RequestIDHeader) {
+			c.requestID = c.prebuilt.Header.Get(c.Config.RequestIDHeader)
+		}
+		c.createHTTPClient()
+		return isEmpty(c.Exception)
+	}
+
+	// request middleware
+	for _, md := range c.beforeRequest {
+		if err := md.fn(c); err != nil {
+			c.Exception = &Exception{
+				CodeLocation:   fileLocation(1),
+				Kind:           KindRequest,
+				PanicError:     err,
+				OccurrenceTime: time.Now().Unix(),
+			}
+			return false
+		}
+	}
+
+	// create
+	c.createRequest()
+	return isEmpty(c.Exception)
+}
+
+// roundTrip prepares the request and executes it, then runs the response
+// middleware, the response body is left unread. It records an Exception and returns a nil response on
+// failure, otherwise the caller must close the body and call cancel, which releases the total deadline,
+// once the body is consumed.
+func (c *Client[T]) roundTrip() (*http.Response, context.CancelFunc) {
+	if !c.prepare() {
+		return nil, nil
+	}
+
+	// record start time
+	startTime := time.Now()
+
+	// apply the total deadline over all attempts
+	cancel := context.CancelFunc(func() {})
+	if c.Config.TotalTimeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(c.Context.Request.Context(), c.Config.TotalTimeout)
+		c.Context.Request = c.Context.Request.WithContext(ctx)
+	}
+
+	// trace the connection of the attempts, and their phases if enabled
+	trace := &traceTimings{}
+	ctx := httptrace.WithClientTrace(c.Context.Request.Context(), trace.clientTrace(c.Config.ClientTrace))
+	c.Context.Request = c.Context.Request.WithContext(ctx)
+
+	// execute
+	resp, err := c.execute()
+	trace.record(c.Meta)
+
+	if err != nil {
+		cancel()
 		c.Exception = &Exception{
 			CodeLocation:   fileLocation(1),
-			FailureReason:  c.Result.Msg,
+			Kind:           classifyError(err),
+			PanicError:     err,
 			OccurrenceTime: time.Now().Unix(),
 		}
+		return nil, nil
 	}
 
+	// record end time
+	duration := time.Since(startTime)
+	c.Meta.Duration = duration
+	if c.Config.Stats != nil {
+		c.Config.Stats.Record(duration)
+	}
+
+	// record received At
+	c.Meta.ReceivedAt = time.Now()
+
+	// response middleware
+	for _, md := range c.afterResponse {
+		if err = md.fn(c); err != nil {
+			cancel()
+			_ = resp.Body.Close()
+			c.Exception = &Exception{
+				CodeLocation:   fileLocation(1),
+				Kind:           KindResponse,
+				PanicError:     err,
+				OccurrenceTime: time.Now().Unix(),
+			}
+			return nil, nil
+		}
+	}
+
+	return resp, cancel
+}
+
+// apiErrors returns the errors array of the response body bs at Config.ErrorsKey, the key defaults to
+// "errors" for a GraphQL request in rest mode. It returns nil if there is no such array.
+func (c *Client[T]) apiErrors(bs []byte) []APIError {
+	key := c.Config.ErrorsKey
+	if isEmptyString(key) && c.graphql && c.Config.IsRestMode {
+		key = "errors"
+	}
+	if isEmptyString(key) || len(bs) == 0 {
+		return nil
+	}
+
+	raw, err := extractPath(c.Config.JSONLoader, bs, key)
+	if err != nil {
+		return nil
+	}
+	return parseAPIErrors(c.jsonLib(), raw)
+}
+
+// failBuild records the Exception e of the request builders (such as an invalid host), which
+// short-circuits the sends until Reset, unlike the Exception of a send which is discarded by the next one.
+func (c *Client[T]) failBuild(e *Exception) {
+	c.buildErr = e
+	c.Exception = e
+}
+
+// recordUndecodableStatus records a KindStatus Exception for a non-2xx response whose body cannot
+// be decoded, with the http status and a snippet of the raw body as the failure reason, instead of
+// the confusing decode error.
+func (c *Client[T]) recordUndecodableStatus() {
+	statusErr := newStatusError(c.Context.Response)
+	reason := statusErr.Error()
+	if snippet := bodySnippet(statusErr.Body, bodySnippetSize); !isEmptyString(snippet) {
+		reason = fmt.Sprintf("%s: %s", reason, snippet)
+	}
+
+	c.Exception = &Exception{
+		CodeLocation:   fileLocation(2),
+		Kind:           KindStatus,
+		FailureReason:  reason,
+		StatusError:    statusErr,
+		OccurrenceTime: time.Now().Unix(),
+	}
+}
+
+// execute sends the request of the client instance, and retries it on network errors and
+// retryable statuses (429 and 5xx) up to Config.RetryCount times, waiting Config.RetryWait between
+// the attempts. The retries stop once the deadline of the request context would be exceeded, in
+// which case the result of the last attempt is returned.
+func (c *Client[T]) execute() (*http.Response, error) {
+	req := c.Context.Request
+	for attempt := 0; ; attempt++ {
+		c.retries = attempt
+
+		// rewind the request body consumed by the previous attempt
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		// a streamed body (such as SetBodyReader) cannot be rewound, so it is not retried
+		rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+		resp, err := c.Context.HttpClient.Do(req)
+		if attempt >= c.Config.RetryCount || !rewindable || !isRetryable(resp, err) {
+			return resp, err
+		}
+
+		// a rate limited or unavailable server tells how long to wait with the Retry-After header
+		wait := c.Config.RetryWait
+		if after, ok := retryAfter(resp, time.Now()); ok {
+			wait = after
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+
+		// discard the response of the failed attempt
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		if c.Config.IsDebug {
+			c.ChalkPrintf(LogLevelDebug, "retry the request (%d/%d) in %s", attempt+1, c.Config.RetryCount, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RedirectHistory returns the redirects followed by the last request, in order, such as the hops of an
+// authentication flow. It returns nil if the request was not redirected.
+func (c *Client[T]) RedirectHistory() []RedirectHop {
+	return c.Meta.Redirects
+}
+
+// recordRedirect is the CheckRedirect function of the http client, it records the redirect hops and
+// stops after Config.MaxRedirects redirects, or as soon as a url is redirected to twice (a loop).
+func (c *Client[T]) recordRedirect(req *http.Request, via []*http.Request) error {
+	if c.Config.MaxRedirects < 0 {
+		return http.ErrUseLastResponse
+	}
+
+	hop := RedirectHop{
+		URL:      via[len(via)-1].URL.String(),
+		Location: req.URL.String(),
+	}
+	if req.Response != nil {
+		hop.StatusCode = req.Response.StatusCode
+	}
+	c.Meta.Redirects = append(c.Meta.Redirects, hop)
+
+	// the same url requested with another method, such as a post redirected to a get, is not a loop
+	for _, prev := range via {
+		if prev.Method == req.Method && prev.URL.String() == hop.Location {
+			return fmt.Errorf("%w: %s", ErrRedirectLoop, hop.Location)
+		}
+	}
+
+	limit := c.Config.MaxRedirects
+	if limit == 0 {
+		limit = maxRedirects
+	}
+	if len(via) >= limit {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, limit)
+	}
+	return nil
+}
+
+// RequestID returns the request id sent with the last request, see WithRequestID.
+// It returns an empty string if the request id is disabled or no request was created yet.
+func (c *Client[T]) RequestID() string {
+	return c.requestID
+}
+
+// allowEmptyBody reports whether an empty response body is legitimate for the request,
+// that is a 204 No Content response, or a HEAD/OPTIONS request which carries no body.
+func (c *Client[T]) allowEmptyBody() bool {
+	if c.Context.Response.Status == http.StatusNoContent {
+		return true
+	}
+
+	return c.Meta.Method == MethodHead || c.Meta.Method == MethodOptions
+}
+
+// Reset clears the state left by the previous Send, so that one configured client instance can be
+// reused across endpoints (and its connections with it). Set the new request (such as SetRequest)
+// after calling Reset, then Send again.
+//
+// The following fields are reset:
+//   - Exception: the recorded exception, including the one of the request builders (such as an invalid host)
+//   - Result: the decoded response
+//   - Context.Response: the raw response
+//   - Meta.Url, Meta.Duration, Meta.ReceivedAt and Meta.Redirects: the url, timing and redirects of the previous request
+//   - Meta.DNSTime, Meta.ConnectTime, Meta.TLSTime, Meta.TTFB and Meta.ConnReused: the traced connection of the previous request
+//   - the values stored by Set
+//   - the idempotency key, see SetIdempotencyKey and SetAutoIdempotencyKey
+//
+// The Config, the hooks, the headers, the cookies, the authorization, the query and path parameters
+// and the payload are preserved.
+func (c *Client[T]) Reset() *Client[T] {
+	c.Exception = &Exception{}
+	c.buildErr = nil
+	c.Result = &RESTFulResp[T]{}
+	c.Context.Response = &Response{}
+	c.Meta.Url = ""
+	c.Meta.Duration = 0
+	c.Meta.ReceivedAt = time.Time{}
+	c.Meta.Redirects = nil
+	c.Meta.DNSTime, c.Meta.ConnectTime, c.Meta.TLSTime, c.Meta.TTFB = 0, 0, 0, 0
+	c.Meta.ConnReused = false
+	c.store = nil
+	c.idempotency, c.autoIdemKey, c.idemKeySent = "", false, false
+
 	return c
 }
 
+// Unwrap returns the client instance and a description of the failure reason, if any, it panics with
+// the message (a string) of the recorded error if the request failed with an error, such as a network
+// failure. Prefer Try to handle the error without panicking, its error wraps the recorded error.
 func (c *Client[T]) Unwrap() (*Client[T], string) {
 	if c.Exception.PanicError != nil {
 		panic(c.Exception.PanicError.Error())
@@ -397,10 +1026,72 @@ func (c *Client[T]) Unwrap() (*Client[T], string) {
 	return c, ""
 }
 
+// Try returns the data of the response and a single error composed from the Exception, without
+// panicking, which is the idiomatic Go counterpart of Unwrap.
+// The error is, in order of precedence:
+//   - the Exception for network, timeout, decode and other request failures, which wraps the recorded
+//     error, so that errors.Is(err, context.DeadlineExceeded) inspects the cause
+//   - the StatusError for an unexpected http status, annotated with the business message if any
+//   - a BusinessError if the business code does not match the success code (in rest mode)
+//
+// The error is nil if the request succeeded.
+func (c *Client[T]) Try() (T, error) {
+	e := c.Exception
+	switch {
+	case e.PanicError != nil:
+		return c.Data(), e
+	case e.StatusError != nil && !isEmpty(e.FailureReason) && e.FailureReason != e.StatusError.Error():
+		return c.Data(), fmt.Errorf("%s: %w", e.FailureReason, e.StatusError)
+	case e.StatusError != nil:
+		return c.Data(), e.StatusError
+	case !isEmpty(e.FailureReason):
+		return c.Data(), errors.New(e.FailureReason)
+	case !c.isBusinessOk():
+		return c.Data(), &BusinessError{Code: c.Result.Code, Msg: c.Result.Msg}
+	}
+	return c.Data(), nil
+}
+
+// IsOk reports whether the request succeeded, that is no Exception was recorded and the business
+// code matches one of the success codes (in rest mode), like a nil error of Try.
+func (c *Client[T]) IsOk() bool {
+	return isEmpty(c.Exception) && c.isBusinessOk()
+}
+
+// IsErr reports whether the request failed, it is the opposite of IsOk.
+func (c *Client[T]) IsErr() bool {
+	return !c.IsOk()
+}
+
+// UnwrapOr returns the data of the response if the request succeeded, otherwise the default value
+// def, without panicking like Unwrap.
+func (c *Client[T]) UnwrapOr(def T) T {
+	if c.IsErr() {
+		return def
+	}
+	return c.Data()
+}
+
 func (c *Client[T]) Data() T {
 	return c.Result.Data
 }
 
+// RawBytes returns the raw response body bytes, it returns nil if the request has not been sent.
+func (c *Client[T]) RawBytes() []byte {
+	if c.Context.Response == nil {
+		return nil
+	}
+	return c.Context.Response.bs
+}
+
+// RawText returns the raw response body text, it returns an empty string if the request has not been sent.
+func (c *Client[T]) RawText() string {
+	if c.Context.Response == nil {
+		return ""
+	}
+	return c.Context.Response.text
+}
+
 func (c *Client[T]) EchoQPS() float64 {
 	seconds := c.Meta.Duration.Seconds()
 	qps := float64(1) / seconds
@@ -424,6 +1115,101 @@ func (c *Client[T]) EchoProto() string {
 	return c.Context.Response.R.Proto
 }
 
+// ContentLength returns the Content-Length of the response as reported by the server, which is
+// useful for HEAD requests. It returns -1 if the length is unknown or the request has not been sent.
+func (c *Client[T]) ContentLength() int64 {
+	if c.Context.Response == nil || c.Context.Response.R == nil {
+		return -1
+	}
+	return c.Context.Response.R.ContentLength
+}
+
+// RespHeaders returns the headers of the response, it returns nil if the request has not been sent.
+func (c *Client[T]) RespHeaders() http.Header {
+	if c.Context.Response == nil || c.Context.Response.R == nil {
+		return nil
+	}
+	return c.Context.Response.R.Header
+}
+
+// NotModified reports whether the server responded 304 Not Modified to a conditional request (see
+// SetIfNoneMatch), that is the data cached by the caller is still valid. Such a response is not an
+// Exception, and its data is the zero value of T.
+func (c *Client[T]) NotModified() bool {
+	return c.Context.Response != nil && c.Context.Response.Status == http.StatusNotModified
+}
+
+// ETag returns the ETag header of the response, to be stored with the data and sent back with
+// SetIfNoneMatch. It returns an empty string if there is no such header.
+func (c *Client[T]) ETag() string {
+	return c.RespHeaders().Get(HeaderETagKey)
+}
+
+// AllowedMethods returns the methods supported by the resource, parsed from the Allow header of the
+// response (typically to an OPTIONS request), or from the Access-Control-Allow-Methods header of a
+// CORS preflight response if there is no Allow header.
+// It returns nil if none of these headers is present.
+func (c *Client[T]) AllowedMethods() []string {
+	headers := c.RespHeaders()
+	if headers == nil {
+		return nil
+	}
+
+	allow := headers.Get(HeaderAllowKey)
+	if isEmpty(allow) {
+		allow = headers.Get(HeaderAllowMethodsKey)
+	}
+	if isEmpty(allow) {
+		return nil
+	}
+
+	var methods []string
+	for _, m := range strings.Split(allow, ",") {
+		if m = strings.TrimSpace(m); !isEmpty(m) {
+			methods = append(methods, strings.ToUpper(m))
+		}
+	}
+	return methods
+}
+
+// IsSuccessStatus reports whether the http status code of the response is accepted as a success.
+// By default any 2xx status is accepted, which can be changed by the WithAcceptStatus function.
+func (c *Client[T]) IsSuccessStatus() bool {
+	return isAcceptStatus(c.Context.Response.Status, c.Config.AcceptStatuses)
+}
+
+// TLSConfig returns the tls configuration of the connections of the client instance, built from SkipTLS
+// and CertPins, for the integrations which dial the server themselves, such as a websocket handshake.
+func (c *Client[T]) TLSConfig() *tls.Config {
+	return tlsClientConfig(c.Config)
+}
+
+// TLSInfo returns the TLS connection state of the completed request, such as the negotiated version,
+// the cipher suite and the peer certificate chain. It returns nil for a plain http request, or if the
+// request has not been sent.
+func (c *Client[T]) TLSInfo() *tls.ConnectionState {
+	if c.Context.Response == nil || c.Context.Response.R == nil {
+		return nil
+	}
+	return c.Context.Response.R.TLS
+}
+
+// EchoTLS returns a summary of the TLS connection of the completed request, made of the version,
+// the cipher suite and the subject of the peer certificate, such as
+// "TLS 1.3, TLS_AES_128_GCM_SHA256, CN=example.org". It returns "-" if there is no TLS connection.
+func (c *Client[T]) EchoTLS() string {
+	state := c.TLSInfo()
+	if state == nil {
+		return "-"
+	}
+
+	summary := fmt.Sprintf("%s, %s", tlsVersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if len(state.PeerCertificates) > 0 {
+		summary += ", " + state.PeerCertificates[0].Subject.String()
+	}
+	return summary
+}
+
 func (c *Client[T]) EchoCode() (int, int) {
 	httpStatusCode := c.Context.Response.R.StatusCode
 	restReturnCode := c.Result.Code
@@ -478,8 +1264,15 @@ func (c *Client[T]) Echo() {
 		}
 		output.WriteString(fmt.Sprintf("  Benchmark  : %d\t%d ns/op\n", executions, efficiency))
 		output.WriteString(fmt.Sprintf("  Proto      : %s\n", proto))
+		if !c.Config.SkipTLS && c.TLSInfo() != nil {
+			output.WriteString(fmt.Sprintf("  TLS        : %s\n", c.EchoTLS()))
+		}
+		output.WriteString(fmt.Sprintf("  Headers    : %s\n", formatHeaders(redactHeaders(c.RespHeaders(), c.Config.RedactHeaders))))
 		output.WriteString(fmt.Sprintf("  QPS        : %.6f\n", qps))
 		output.WriteString(fmt.Sprintf("  Duration   : %v\n", durationTime))
+		if c.Config.ClientTrace {
+			output.WriteString(fmt.Sprintf("  Trace      : %s\n", c.EchoTrace()))
+		}
 		output.WriteString(fmt.Sprintf("  Received At: %s\n", receivedAt.Format(time.RFC850)))
 		output.WriteString(fmt.Sprintf("  Body       : %v\n", "-"))
 	}
@@ -490,22 +1283,58 @@ func (c *Client[T]) ToJson(v any) error {
 	if c.Context.Response.length == 0 {
 		return errors.New("pesponse body length is 0")
 	}
-	if err := c.Config.JSONLoader.Unmarshal(c.Context.Response.bs, v); err != nil {
+	if err := c.jsonLib().Unmarshal(c.Context.Response.bs, v); err != nil {
 		return err
 	}
 	return nil
 }
 
+// ScanData unmarshals only the data of the response into v, which is any pointer, regardless of T.
+// In rest mode the data is the field of the envelope named by the configured keys (see WithEnvelopeKeys),
+// and it honors WithDataPath and WithKeyTransform like Send. The code and the msg remain in Result.
+// A response without data leaves v unchanged.
+//
+// Example usage:
+//
+//	var user User
+//	if err := c.ScanData(&user); err != nil {
+//		// handle error
+//	}
+func (c *Client[T]) ScanData(v any) error {
+	if c.Context.Response.length == 0 {
+		return errors.New("response body length is 0")
+	}
+
+	raw, err := c.rawData(c.Context.Response.bs)
+	if err != nil || len(raw) == 0 {
+		return err
+	}
+
+	return c.jsonLib().Unmarshal(raw, v)
+}
+
 type beforeRequest[T any] func(*Client[T]) error
 type afterResponse[T any] func(*Client[T]) error
 
+// hook is a middleware of the client instance, the name is empty unless it is registered by
+// UsePreHookNamed or UsePostHookNamed.
+type hook[T any] struct {
+	name string
+	fn   func(*Client[T]) error
+}
+
+// Names of the pre hooks registered by Default, which can be removed by RemovePreHook
+const (
+	HookDefaultHeaders = "gloria.default-headers" // fills the default headers which are not set
+)
+
 // UsePreHooks request interceptor middleware
 func (c *Client[T]) UsePreHooks(funcs ...beforeRequest[T]) {
 	if c.Config.IsDebug {
 		c.ChalkStr(LogLevelDebug, "inject pre hooks")
 	}
 	for _, fn := range funcs {
-		c.beforeRequest = append(c.beforeRequest, fn)
+		c.beforeRequest = append(c.beforeRequest, hook[T]{fn: fn})
 	}
 }
 
@@ -515,6 +1344,130 @@ func (c *Client[T]) UsePostHooks(funcs ...afterResponse[T]) {
 		c.ChalkStr(LogLevelDebug, "inject post hooks")
 	}
 	for _, fn := range funcs {
-		c.afterResponse = append(c.afterResponse, fn)
+		c.afterResponse = append(c.afterResponse, hook[T]{fn: fn})
 	}
 }
+
+// UseBodyTransform registers a transform of the response body, which runs once the body is read by
+// Send and before it is decoded, such as the unwrapping of a double-encoded envelope or a decryption.
+// The transforms run in order, each one receiving the body returned by the previous one, and the
+// returned body replaces the raw body (see RawBytes). An empty body is not transformed.
+// A failure is recorded as a KindDecode Exception, or by the http status of an unsuccessful response.
+//
+// Example usage:
+//
+//	c.UseBodyTransform(func(b []byte) ([]byte, error) {
+//		var inner string // the envelope is a json string holding the json document
+//		if err := json.Unmarshal(b, &inner); err != nil {
+//			return nil, err
+//		}
+//		return []byte(inner), nil
+//	})
+func (c *Client[T]) UseBodyTransform(fn func(b []byte) ([]byte, error)) {
+	if c.Config.IsDebug {
+		c.ChalkStr(LogLevelDebug, "inject body transform")
+	}
+	c.transforms = append(c.transforms, fn)
+}
+
+// transformBody applies the body transforms to the response body b in order.
+func (c *Client[T]) transformBody(b []byte) ([]byte, error) {
+	for _, fn := range c.transforms {
+		var err error
+		if b, err = fn(b); err != nil {
+			return nil, fmt.Errorf("transform response body: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// UsePreHookNamed registers a request interceptor middleware under a name, so that it can be removed
+// by RemovePreHook. A hook registered under the name of an existing one replaces it in place, keeping
+// its position in the pipeline, otherwise it runs after the existing hooks.
+// The hook registered by Default is named HookDefaultHeaders.
+//
+// Example usage:
+//
+//	c.UsePreHookNamed("auth", func(c *gloria.Client[User]) error {
+//		c.SetBearerAuth(tokens.Current())
+//		return nil
+//	})
+func (c *Client[T]) UsePreHookNamed(name string, fn beforeRequest[T]) {
+	c.beforeRequest = setHook(c.beforeRequest, hook[T]{name: name, fn: fn})
+}
+
+// RemovePreHook removes the request interceptor middleware registered under the name, such as the
+// default headers of Default (HookDefaultHeaders). It is a no-op if there is no such hook.
+func (c *Client[T]) RemovePreHook(name string) {
+	c.beforeRequest = removeHook(c.beforeRequest, name)
+}
+
+// UsePostHookNamed registers a response interceptor middleware under a name, so that it can be removed
+// by RemovePostHook. It is the counterpart of UsePreHookNamed.
+func (c *Client[T]) UsePostHookNamed(name string, fn afterResponse[T]) {
+	c.afterResponse = setHook(c.afterResponse, hook[T]{name: name, fn: fn})
+}
+
+// RemovePostHook removes the response interceptor middleware registered under the name.
+// It is a no-op if there is no such hook.
+func (c *Client[T]) RemovePostHook(name string) {
+	c.afterResponse = removeHook(c.afterResponse, name)
+}
+
+// setHook replaces the hook of hooks with the same name as h, or appends h, an unnamed h is appended.
+func setHook[T any](hooks []hook[T], h hook[T]) []hook[T] {
+	for i := range hooks {
+		if !isEmptyString(h.name) && hooks[i].name == h.name {
+			hooks[i] = h
+			return hooks
+		}
+	}
+	return append(hooks, h)
+}
+
+// removeHook removes the hook of hooks with the name, the unnamed hooks cannot be removed.
+func removeHook[T any](hooks []hook[T], name string) []hook[T] {
+	if isEmptyString(name) {
+		return hooks
+	}
+
+	kept := hooks[:0]
+	for _, h := range hooks {
+		if h.name != name {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
+
+// Set stores a value under the key in the client instance, as a scratch space shared by the hooks, such
+// as a start time or a tracing span set by a pre hook and read by a post hook, without globals.
+// The values are kept across the calls of Send until Reset.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	c.UsePreHooks(func(c *gloria.Client[User]) error {
+//		c.Set("start", time.Now())
+//		return nil
+//	})
+//	c.UsePostHooks(func(c *gloria.Client[User]) error {
+//		if start, ok := c.Get("start"); ok {
+//			log.Printf("took %s", time.Since(start.(time.Time)))
+//		}
+//		return nil
+//	})
+func (c *Client[T]) Set(key string, value any) *Client[T] {
+	if c.store == nil {
+		c.store = H{}
+	}
+	c.store[key] = value
+
+	return c
+}
+
+// Get returns the value stored under the key by Set, it returns false if there is no such value.
+func (c *Client[T]) Get(key string) (any, bool) {
+	value, ok := c.store[key]
+	return value, ok
+}
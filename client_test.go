@@ -0,0 +1,1423 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer starts a local server that replies with the given status code and body.
+func newTestServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContentTypeKey, JsonContentType)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestSend_StatusErrorInHTTPMode(t *testing.T) {
+	srv := newTestServer(t, http.StatusNotFound, `{"error":"not found"}`)
+
+	c := NewHTTP[H]().SetRequest(MethodGet, srv.URL+"/users/1").Send()
+
+	se := c.Exception.StatusError
+	if se == nil {
+		t.Fatal("expected a status error to be recorded")
+	}
+	if se.StatusCode != http.StatusNotFound {
+		t.Errorf("status code = %d, want %d", se.StatusCode, http.StatusNotFound)
+	}
+	if string(se.Body) != `{"error":"not found"}` {
+		t.Errorf("body = %q", se.Body)
+	}
+	if c.Exception.FailureReason != se.Error() {
+		t.Errorf("failure reason = %q, want %q", c.Exception.FailureReason, se.Error())
+	}
+
+	var target *StatusError
+	if !errors.As(errors.Join(errors.New("wrapped"), se), &target) {
+		t.Error("status error should be reachable through errors.As")
+	}
+}
+
+func TestSend_AcceptStatus(t *testing.T) {
+	srv := newTestServer(t, http.StatusCreated, `{"code":0,"msg":"created","data":{"id":1}}`)
+
+	c := New[H]().SetRequest(MethodPost, srv.URL+"/users").Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("201 should be accepted by default, got %+v", c.Exception)
+	}
+
+	c = New[H]().Optional(WithAcceptStatus[H](http.StatusOK)).SetRequest(MethodPost, srv.URL+"/users").Send()
+	if c.Exception.StatusError == nil || c.IsSuccessStatus() {
+		t.Fatal("201 should be rejected when only 200 is accepted")
+	}
+}
+
+func TestSend_NoContent(t *testing.T) {
+	srv := newTestServer(t, http.StatusNoContent, "")
+
+	c := New[H]().SetRequest(MethodDelete, srv.URL+"/users/1").Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("204 should not be an error, got %+v", c.Exception)
+	}
+	if c.Data() != nil {
+		t.Errorf("data = %v, want zero value", c.Data())
+	}
+}
+
+func TestSend_ChunkedBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0,`))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(`"msg":"ok","data":{"id":1}}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodGet, srv.URL+"/users/1").Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("chunked body should be decoded, got %+v", c.Exception)
+	}
+	if c.Context.Response.R.ContentLength != -1 {
+		t.Fatalf("expected a chunked response, content length = %d", c.Context.Response.R.ContentLength)
+	}
+	if c.Context.Response.length != int64(len(c.Context.Response.bs)) {
+		t.Errorf("length = %d, want %d", c.Context.Response.length, len(c.Context.Response.bs))
+	}
+}
+
+func TestClient_RawBody(t *testing.T) {
+	c := NewHTTP[H]()
+	if c.RawBytes() != nil || c.RawText() != "" {
+		t.Fatal("raw body should be empty before Send")
+	}
+
+	srv := newTestServer(t, http.StatusBadGateway, "<html>bad gateway</html>")
+	c.SetRequest(MethodGet, srv.URL+"/ping").Send()
+
+	if c.RawText() != "<html>bad gateway</html>" || string(c.RawBytes()) != c.RawText() {
+		t.Errorf("raw text = %q", c.RawText())
+	}
+}
+
+func TestSend_PathParams(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().
+		SetPathParams(SMap{"team": "core"}).
+		SetRequest(MethodGet, srv.URL+"/orgs/:org/teams/:team").
+		SetPathParam("org", "acme").
+		Send()
+	if !isEmpty(c.Exception) || gotPath != "/orgs/acme/teams/core" {
+		t.Fatalf("path = %q, exception = %+v", gotPath, c.Exception)
+	}
+
+	c = New[H]().SetRequest(MethodGet, srv.URL+"/orgs/:org").Send()
+	if c.Exception.PanicError == nil {
+		t.Fatal("an unfilled placeholder should be recorded as an exception")
+	}
+}
+
+func TestSend_EscapedPathParams(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodGet, srv.URL+"/api/files/:id/:sid", "a/b", "hello world").Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if gotPath != "/api/files/a%2Fb/hello%20world" {
+		t.Errorf("path = %q", gotPath)
+	}
+}
+
+func TestSend_MalformedURL(t *testing.T) {
+	c := New[H]().SetURL(ProtocolHttp, "localhost:8080", "/api", "/files/%zz").SetMethod(MethodGet).Send()
+
+	if c.Exception.PanicError == nil || !strings.Contains(c.Exception.PanicError.Error(), "invalid request url") {
+		t.Fatalf("expected an invalid url exception, got %+v", c.Exception)
+	}
+}
+
+func TestSetHost_IPv6(t *testing.T) {
+	c := New[H]()
+
+	if c.SetHost("[2001:db8::1]:443"); c.urls.host != "[2001:db8::1]:443" {
+		t.Errorf("host = %q", c.urls.host)
+	}
+	if c.SetHost("::1"); c.urls.host != "[::1]" {
+		t.Errorf("host = %q", c.urls.host)
+	}
+}
+
+func TestSetHost_InvalidHost(t *testing.T) {
+	c := New[H]().SetRequest(MethodGet, "http://bad_host!/ping").Send()
+	if c.Exception.PanicError == nil {
+		t.Fatal("an invalid host should be recorded as an exception")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("an invalid host should panic in strict mode")
+		}
+	}()
+	New[H]().Optional(WithStrictURL[H](true)).SetHost("bad_host!")
+}
+
+func TestSend_RetryAfterFailure(t *testing.T) {
+	var hits int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0,"data":{"ok":true}}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodGet, srv.URL).Send()
+	if c.Exception.Kind != KindStatus {
+		t.Fatalf("first send kind = %q, want %q", c.Exception.Kind, KindStatus)
+	}
+
+	// the exception of the failed send does not short-circuit the next one
+	c.Send()
+	if hits != 2 {
+		t.Fatalf("hits = %d, want 2", hits)
+	}
+	if !isEmpty(c.Exception) || c.Data()["ok"] != true {
+		t.Fatalf("second send = %+v %v, want a success", c.Exception, c.Data())
+	}
+
+	// an invalid host keeps short-circuiting until Reset
+	c.SetHost("bad_host!").Send()
+	c.Send()
+	if hits != 2 || c.Exception.Kind != KindRequest {
+		t.Fatalf("hits = %d kind = %q, want no request and %q", hits, c.Exception.Kind, KindRequest)
+	}
+}
+
+func TestClient_ResolveURL(t *testing.T) {
+	c := New[H]().Optional(WithBaseURL[H]("https://example.org/api/v1"))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users", "https://example.org/api/v1/users"},
+		{"users/1?page=2", "https://example.org/api/v1/users/1?page=2"},
+		{"http://other.org/ping", "http://other.org/ping"},
+	}
+	for _, tt := range tests {
+		if got := c.resolveURL(tt.path); got != tt.want {
+			t.Errorf("resolveURL(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	srv := newTestServer(t, http.StatusOK, `{"code":0}`)
+	c = New[H]().Optional(WithBaseURL[H](srv.URL+"/api")).SetRequest(MethodGet, "/users/:id", "1").Send()
+	if !isEmpty(c.Exception) || c.Meta.Url != srv.URL+"/api/users/1" {
+		t.Errorf("url = %q, exception = %+v", c.Meta.Url, c.Exception)
+	}
+}
+
+func TestSetBaseURI_Trim(t *testing.T) {
+	for _, baseURI := range []string{"/api", "/api/", "/api-", "/api/-", "/api-/"} {
+		if c := New[H]().SetBaseURI(baseURI); c.urls.baseURI != "/api" {
+			t.Errorf("SetBaseURI(%q) = %q, want %q", baseURI, c.urls.baseURI, "/api")
+		}
+	}
+}
+
+func TestSetRequest_MergeQueryParams(t *testing.T) {
+	c := New[H]().
+		SetQueryParam("a", "0").
+		SetQueryParam("c", "3").
+		SetRequest(MethodGet, "https://example.org/users?a=1&b=2").
+		SetQueryParam("b", "20").
+		SetQueryParams(H{"d": 4})
+
+	want := SMap{"a": "1", "b": "20", "c": "3", "d": "4"}
+	for k, v := range want {
+		if got := c.Query(k); got != v {
+			t.Errorf("query %q = %q, want %q", k, got, v)
+		}
+	}
+	if len(c.QueryParams()) != len(want) {
+		t.Errorf("query params = %v, want %v", c.QueryParams(), want)
+	}
+}
+
+func TestRemoveQueryParamAndHeader(t *testing.T) {
+	c := New[H]().
+		SetRequest(MethodGet, "https://example.org/users?a=1&b=2").
+		SetRawQuery("sig=x").
+		SetBearerAuth("token").
+		SetUserAgent("agent/1.0").
+		SetHeaders(H{"x-trace": "1", "X-Org": "acme"})
+
+	c.QueryParams()["a"] = "mutated"
+	if c.Query("a") != "1" {
+		t.Errorf("query a = %q, want the client state untouched by QueryParams", c.Query("a"))
+	}
+
+	c.RemoveQueryParam("a").RemoveHeader("X-Trace").RemoveHeader("authorization").RemoveHeader("User-Agent")
+	req, err := c.BuildRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.RawQuery != "b=2&sig=x" {
+		t.Errorf("query = %q, want b and the raw query", req.URL.RawQuery)
+	}
+	for _, k := range []string{"X-Trace", HeaderAuthorizationKey, HeaderUserAgentKey} {
+		if v := req.Header.Get(k); v != "" {
+			t.Errorf("header %s = %q, want it removed", k, v)
+		}
+	}
+	if req.Header.Get("X-Org") != "acme" {
+		t.Errorf("header X-Org = %q, want it kept", req.Header.Get("X-Org"))
+	}
+
+	if c.ClearQueryParams(); c.QueryParams() != nil {
+		t.Errorf("query params = %v, want none", c.QueryParams())
+	}
+	if _, err = c.BuildRequest(); err != nil || c.Meta.Url != "https://example.org/users" {
+		t.Errorf("url = %s, %v, want no query", c.Meta.Url, err)
+	}
+}
+
+func TestWithRawCommaParams(t *testing.T) {
+	params := H{"mime_types": []string{"png", "gif"}, "q": "a b"}
+
+	c := New[H]().SetRequest(MethodGet, "https://api.example.org/images").SetQueryParams(params)
+	if _, err := c.BuildRequest(); err != nil || !strings.Contains(c.Meta.Url, "mime_types=png%2Cgif") {
+		t.Errorf("url = %s, %v, want the encoded commas by default", c.Meta.Url, err)
+	}
+
+	c = New[H](WithRawCommaParams[H](true)).SetRequest(MethodGet, "https://api.example.org/images").SetQueryParams(params)
+	if _, err := c.BuildRequest(); err != nil || c.Meta.Url != "https://api.example.org/images?mime_types=png,gif&q=a+b" {
+		t.Errorf("url = %s, %v, want the literal commas", c.Meta.Url, err)
+	}
+}
+
+func TestSetQueryStruct(t *testing.T) {
+	type Paging struct {
+		Page int `query:"page"`
+	}
+	type filter struct {
+		Paging
+		Status  string    `query:"status,omitempty"`
+		Tags    []string  `query:"tags,omitempty"`
+		IDs     []uint    `query:"ids"`
+		Active  *bool     `query:"active"`
+		Since   time.Time `query:"since,omitempty"`
+		Limit   int64
+		Ignored string `query:"-"`
+	}
+
+	c := New[H]().SetQueryStruct(&filter{Paging: Paging{Page: 2}, IDs: []uint{1, 2}, Limit: 50, Ignored: "x"})
+	want := SMap{"page": "2", "ids": "1,2", "Limit": "50"}
+	if len(c.params) != len(want) {
+		t.Fatalf("params = %v, want %v", c.params, want)
+	}
+	for k, v := range want {
+		if c.params[k] != v {
+			t.Errorf("params[%q] = %q, want %q", k, c.params[k], v)
+		}
+	}
+
+	if c := New[H]().SetQueryStruct("status=open"); c.Exception.Kind != KindRequest {
+		t.Errorf("exception = %+v, want a request error", c.Exception)
+	}
+
+	// the fields of an unexported embedded struct are promoted too
+	type inner struct {
+		Org  string   `query:"org"`
+		Tags []string `query:"tags"`
+	}
+	type outer struct {
+		inner
+		Name string `query:"name"`
+	}
+	c = New[H]().SetQueryStruct(outer{inner{"x", []string{"a", "b"}}, "y"})
+	if !isEmpty(c.Exception) || c.params["org"] != "x" || c.params["tags"] != "a,b" || c.params["name"] != "y" {
+		t.Errorf("params = %v, exception = %+v", c.params, c.Exception)
+	}
+}
+
+func TestSetFormStruct(t *testing.T) {
+	var gotType string
+	var gotForm url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotType = r.Header.Get(HeaderContentTypeKey)
+		_ = r.ParseForm()
+		gotForm = r.PostForm
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	type login struct {
+		Username string   `form:"username"`
+		Scopes   []string `form:"scope"`
+		Remember bool     `form:"remember,omitempty"`
+		Attempts int
+	}
+
+	c := New[H]().
+		SetRequest(MethodPost, srv.URL+"/oauth/token").
+		SetFormStruct(&login{Username: "john doe", Scopes: []string{"read", "write"}, Attempts: 1}).
+		Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+
+	if gotType != FormContentType {
+		t.Errorf("Content-Type = %q, want %q", gotType, FormContentType)
+	}
+	want := url.Values{"username": {"john doe"}, "scope": {"read", "write"}, "Attempts": {"1"}}
+	if !reflect.DeepEqual(gotForm, want) {
+		t.Errorf("form = %v, want %v", gotForm, want)
+	}
+
+	if c := New[H]().SetFormStruct(H{"username": "john"}); c.Exception.Kind != KindRequest {
+		t.Errorf("exception = %+v, want a request error", c.Exception)
+	}
+
+	// the fields of an unexported embedded struct are promoted too
+	type client struct {
+		ClientID string `form:"client_id"`
+	}
+	type grant struct {
+		client
+		GrantType string `form:"grant_type"`
+	}
+	c = New[H]().
+		SetRequest(MethodPost, srv.URL+"/oauth/token").
+		SetFormStruct(grant{client{"app"}, "client_credentials"}).
+		Send()
+	want = url.Values{"client_id": {"app"}, "grant_type": {"client_credentials"}}
+	if !isEmpty(c.Exception) || !reflect.DeepEqual(gotForm, want) {
+		t.Errorf("form = %v, want %v, exception = %+v", gotForm, want, c.Exception)
+	}
+}
+
+func TestSend_RawQuery(t *testing.T) {
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().
+		SetRequest(MethodGet, srv.URL+"/files").
+		SetQueryParam("name", "a b").
+		SetRawQuery("?signature=abc%2Bdef%3D").
+		Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if gotQuery != "name=a+b&signature=abc%2Bdef%3D" {
+		t.Errorf("query = %q", gotQuery)
+	}
+}
+
+func TestSend_Head(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total", "42")
+		w.Header().Set(HeaderContentLengthKey, "128")
+	}))
+	defer srv.Close()
+
+	c := New[H]()
+	if c.ContentLength() != -1 || c.RespHeaders() != nil {
+		t.Fatal("response accessors should be empty before Send")
+	}
+
+	c.SetRequest(MethodHead, srv.URL+"/files/1").Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if c.ContentLength() != 128 || c.RespHeaders().Get("X-Total") != "42" {
+		t.Errorf("content length = %d, headers = %v", c.ContentLength(), c.RespHeaders())
+	}
+}
+
+func TestSend_Options(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderAllowKey, "GET, post,OPTIONS")
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodOptions, srv.URL+"/users").Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+
+	got := strings.Join(c.AllowedMethods(), ",")
+	if got != "GET,POST,OPTIONS" {
+		t.Errorf("allowed methods = %q", got)
+	}
+}
+
+func TestSend_IdempotencyKey(t *testing.T) {
+	var gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get(HeaderIdempotencyKey)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodPost, srv.URL+"/orders").SetAutoIdempotencyKey()
+	if len(c.IdempotencyKey()) != 36 {
+		t.Fatalf("generated key = %q", c.IdempotencyKey())
+	}
+
+	c.Send()
+	if gotKey != c.IdempotencyKey() {
+		t.Errorf("Idempotency-Key header = %q, want %q", gotKey, c.IdempotencyKey())
+	}
+
+	// another request of the reused client has another key
+	first := gotKey
+	c.Send()
+	if gotKey == first || gotKey != c.IdempotencyKey() {
+		t.Errorf("second Idempotency-Key header = %q, want a new key", gotKey)
+	}
+
+	if c.Reset().Send(); gotKey != "" {
+		t.Errorf("Idempotency-Key header after Reset = %q, want none", gotKey)
+	}
+}
+
+func TestSend_Retry(t *testing.T) {
+	var attempts int
+	keys := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		keys[r.Header.Get(HeaderIdempotencyKey)] = true
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().
+		Optional(WithRetry[H](3, time.Millisecond)).
+		SetRequest(MethodPost, srv.URL+"/orders").
+		SetPayload(H{"id": 1}).
+		SetAutoIdempotencyKey().
+		Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if attempts != 3 || len(keys) != 1 {
+		t.Errorf("attempts = %d, idempotency keys = %v", attempts, keys)
+	}
+}
+
+func TestSend_RetryAfter(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path == "/slow" {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if attempts < 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	// the Retry-After header replaces the wait of an hour
+	c := New[H](WithRetry[H](1, time.Hour)).SetRequest(MethodGet, srv.URL+"/fast").Send()
+	if !isEmpty(c.Exception) || attempts != 2 {
+		t.Fatalf("attempts = %d, exception = %+v", attempts, c.Exception)
+	}
+
+	// a wait beyond the total deadline gives up at once
+	attempts = 0
+	start := time.Now()
+	c = New[H](WithRetry[H](1, time.Millisecond), WithTotalDeadline[H](time.Second)).SetRequest(MethodGet, srv.URL+"/slow").Send()
+	if attempts != 1 || c.Exception.StatusError == nil || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("attempts = %d, exception = %+v, want the 429 without retrying", attempts, c.Exception)
+	}
+}
+
+func TestSend_BodyReader(t *testing.T) {
+	var gotType string
+	var gotLength int64
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotType, gotLength = r.Header.Get(HeaderContentTypeKey), r.ContentLength
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	body := strings.Repeat("x", 1<<20)
+	c := New[H]().
+		SetRequest(MethodPut, srv.URL+"/upload").
+		SetPayload(H{"ignored": true}).
+		SetBodyReader(io.MultiReader(strings.NewReader(body)), "application/octet-stream", int64(len(body))).
+		Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if gotType != "application/octet-stream" || gotLength != int64(len(body)) || gotBody != body {
+		t.Errorf("content type = %q, length = %d, body length = %d", gotType, gotLength, len(gotBody))
+	}
+}
+
+func TestSend_RequestCompression(t *testing.T) {
+	var gotEncoding, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding, gotBody = r.Header.Get(HeaderContentEncodingKey), ""
+		body := io.Reader(r.Body)
+		switch gotEncoding {
+		case CompressionGzip:
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("gzip.NewReader() error = %v", err)
+				return
+			}
+			body = gz
+		case CompressionDeflate:
+			zr, err := zlib.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("zlib.NewReader() error = %v", err)
+				return
+			}
+			body = zr
+		}
+		b, _ := io.ReadAll(body)
+		gotBody = string(b)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		compression  string
+		payload      H
+		wantEncoding string
+	}{
+		{"small", CompressionGzip, H{"id": 1}, ""},
+		{"large gzip", CompressionGzip, H{"text": strings.Repeat("x", 2048)}, CompressionGzip},
+		{"large deflate", CompressionDeflate, H{"text": strings.Repeat("x", 2048)}, CompressionDeflate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New[H]().
+				Optional(WithRequestCompression[H](tt.compression)).
+				SetRequest(MethodPost, srv.URL).
+				SetPayload(tt.payload).
+				Send()
+			if !isEmpty(c.Exception) {
+				t.Fatalf("unexpected exception: %+v", c.Exception)
+			}
+			want, _ := json.Marshal(tt.payload)
+			if gotEncoding != tt.wantEncoding || gotBody != string(want) {
+				t.Errorf("encoding = %q, body length = %d, want %q, %d", gotEncoding, len(gotBody), tt.wantEncoding, len(want))
+			}
+		})
+	}
+}
+
+func TestSendWith_RequestHeaders(t *testing.T) {
+	var got []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodGet, srv.URL).SetHeader("X-Tenant", "base")
+	c.SendWith(WithRequestHeaders(H{"X-Request-ID": 42, "X-Tenant": "override"}))
+	c.Reset().Send()
+
+	if len(got) != 2 {
+		t.Fatalf("requests = %d, want 2", len(got))
+	}
+	if got[0].Get("X-Request-ID") != "42" || got[0].Get("X-Tenant") != "override" {
+		t.Errorf("first request headers = %v", got[0])
+	}
+	if got[1].Get("X-Request-ID") != "" || got[1].Get("X-Tenant") != "base" {
+		t.Errorf("second request headers = %v", got[1])
+	}
+}
+
+func TestClient_TLSInfo(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().Optional(WithSkipTLS[H](true)).SetRequest(MethodGet, srv.URL).Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+
+	state := c.TLSInfo()
+	if state == nil || !state.HandshakeComplete || len(state.PeerCertificates) == 0 {
+		t.Fatalf("TLSInfo() = %+v", state)
+	}
+	if got := c.EchoTLS(); !strings.HasPrefix(got, "TLS 1.") || !strings.Contains(got, "O=Acme Co") {
+		t.Errorf("EchoTLS() = %q", got)
+	}
+
+	plain := New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusOK, `{"code":0}`).URL).Send()
+	if plain.TLSInfo() != nil || plain.EchoTLS() != "-" {
+		t.Errorf("TLSInfo() = %+v, want nil for plain http", plain.TLSInfo())
+	}
+}
+
+func TestSend_CertPinning(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	pinned := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	c := New[H]().
+		Optional(WithSkipTLS[H](true), WithCertPinning[H]([]string{pinned})).
+		SetRequest(MethodGet, srv.URL).
+		Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+
+	c = New[H]().
+		Optional(WithSkipTLS[H](true), WithCertPinning[H]([]string{strings.Repeat("00", sha256.Size)})).
+		SetRequest(MethodGet, srv.URL).
+		Send()
+	if !errors.Is(c.Exception.PanicError, ErrCertNotPinned) {
+		t.Fatalf("exception = %+v, want ErrCertNotPinned", c.Exception)
+	}
+}
+
+func TestAppendUserAgent(t *testing.T) {
+	c := New[H]().AppendUserAgent("MyService/1.2").AppendUserAgent(" ")
+	want := getUserAgent() + " MyService/1.2"
+	if c.headers.userAgent != want {
+		t.Errorf("user agent = %q, want %q", c.headers.userAgent, want)
+	}
+
+	c = New[H]().SetUserAgent("MyApp/1.0").AppendUserAgent("MyService/1.2")
+	if c.headers.userAgent != "MyApp/1.0 MyService/1.2" {
+		t.Errorf("user agent = %q, want %q", c.headers.userAgent, "MyApp/1.0 MyService/1.2")
+	}
+}
+
+func TestHttpClientDefaultConf_Transport(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	for _, logged := range []bool{false, true} {
+		c := New[H]().Optional(WithSkipTLS[H](true), WithUseLogger[H](logged))
+		c.Config.Logger = log.New(io.Discard, "", 0)
+		if !logged {
+			c.Config.Logger = nil
+		}
+		c.SetRequest(MethodGet, srv.URL).Send()
+
+		// The self-signed certificate is only accepted if the custom transport is used
+		if !isEmpty(c.Exception) {
+			t.Fatalf("logged = %v: unexpected exception: %+v", logged, c.Exception)
+		}
+
+		tr := c.Context.HttpClient.Transport
+		if lt, ok := tr.(*loggedTransport); ok {
+			tr = lt.transport
+		}
+		if ht, ok := tr.(*http.Transport); !ok || !ht.TLSClientConfig.InsecureSkipVerify || ht.MaxIdleConnsPerHost != 10 {
+			t.Errorf("logged = %v: transport = %#v, want the custom transport", logged, tr)
+		}
+	}
+}
+
+func TestSend_DefaultHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H](
+		WithDefaultHeaders[H](H{"X-Api-Key": "key", "X-Tenant": "base"}),
+		WithDefaultHeaders[H](H{"X-Trace": 1}),
+	)
+	c.SetRequest(MethodGet, srv.URL).SetHeader("X-Tenant", "request").Send()
+
+	if got.Get("X-Api-Key") != "key" || got.Get("X-Trace") != "1" || got.Get("X-Tenant") != "request" {
+		t.Errorf("headers = %v", got)
+	}
+}
+
+func TestDefault_HeaderMerge(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := Default[H]().SetRequest(MethodGet, srv.URL).SetHeader("X-Custom", "1").SetHeader("accept", "text/csv")
+	c.Config.Logger = nil
+	c.Send()
+
+	if got.Get("X-Custom") != "1" || got.Get(HeaderAcceptKey) != "text/csv" {
+		t.Errorf("custom headers = %v", got)
+	}
+	if got.Get(HeaderContentTypeKey) != JsonContentType || got.Get(HeaderContentLanguageKey) != LocaleEn ||
+		got.Get(HeaderUserAgentKey) != getUserAgent() {
+		t.Errorf("default headers = %v", got)
+	}
+}
+
+func TestSend_RedirectHistory(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/login", http.RedirectHandler("/sso", http.StatusFound))
+	mux.Handle("/sso", http.RedirectHandler("/home", http.StatusMovedPermanently))
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodGet, srv.URL+"/login").Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+
+	want := []RedirectHop{
+		{StatusCode: http.StatusFound, URL: srv.URL + "/login", Location: srv.URL + "/sso"},
+		{StatusCode: http.StatusMovedPermanently, URL: srv.URL + "/sso", Location: srv.URL + "/home"},
+	}
+	got := c.RedirectHistory()
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("RedirectHistory() = %+v, want %+v", got, want)
+	}
+
+	if c.Reset().RedirectHistory() != nil {
+		t.Error("RedirectHistory() should be cleared by Reset")
+	}
+}
+
+func TestSend_MaxRedirects(t *testing.T) {
+	var hits sync.Map
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := hits.LoadOrStore(r.URL.Path, new(int))
+		*n.(*int)++
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.Handle("/b", http.RedirectHandler("/a", http.StatusFound))
+	mux.Handle("/login", http.RedirectHandler("/sso", http.StatusFound))
+	mux.Handle("/sso", http.RedirectHandler("/home", http.StatusFound))
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := New[H]().Optional(WithRetry[H](2, time.Millisecond)).SetRequest(MethodGet, srv.URL+"/a").Send()
+	if !errors.Is(c.Exception.PanicError, ErrRedirectLoop) {
+		t.Fatalf("exception = %+v, want a redirect loop", c.Exception)
+	}
+	if n, _ := hits.Load("/a"); *n.(*int) != 1 {
+		t.Errorf("/a requested %d times, want 1 (a loop is not retried)", *n.(*int))
+	}
+
+	c = New[H]().Optional(WithMaxRedirects[H](1)).SetRequest(MethodGet, srv.URL+"/login").Send()
+	if !errors.Is(c.Exception.PanicError, ErrTooManyRedirects) {
+		t.Errorf("exception = %+v, want too many redirects", c.Exception)
+	}
+
+	c = NewHTTP[H]().Optional(WithMaxRedirects[H](-1)).SetRequest(MethodGet, srv.URL+"/login").Send()
+	if c.Context.Response.Status != http.StatusFound || c.RedirectHistory() != nil {
+		t.Errorf("status = %d, redirects = %+v, want the redirect response", c.Context.Response.Status, c.RedirectHistory())
+	}
+}
+
+func TestSend_OmitNull(t *testing.T) {
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	type user struct {
+		Name   string  `json:"name"`
+		Parent *string `json:"parent"`
+	}
+
+	c := New[H](WithOmitNull[H](true)).SetRequest(MethodPost, srv.URL).SetPayload(user{Name: "a"}).Send()
+	if !isEmpty(c.Exception) || gotBody != `{"name":"a"}` {
+		t.Errorf("body = %s, exception %+v", gotBody, c.Exception)
+	}
+}
+
+func TestSend_TotalDeadline(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"code":503,"msg":"unavailable"}`))
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	c := New[H]().
+		Optional(WithRetry[H](10, 40*time.Millisecond), WithTotalDeadline[H](100*time.Millisecond)).
+		SetRequest(MethodGet, srv.URL+"/ping").
+		Send()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("the deadline is not honored, took %s", elapsed)
+	}
+	if attempts >= 10 || c.Exception.StatusError == nil {
+		t.Errorf("attempts = %d, exception = %+v", attempts, c.Exception)
+	}
+}
+
+func TestClient_Reset(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get(HeaderAuthorizationKey)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte(`{"code":0,"data":{"path":"` + r.URL.Path + `"}}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetBearerAuth("token").SetRequest(MethodGet, srv.URL+"/missing").Send()
+	if c.Exception.StatusError == nil {
+		t.Fatalf("expected a status error, got %+v", c.Exception)
+	}
+
+	c.Reset().SetRequest(MethodGet, srv.URL+"/users").Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception after reset: %+v", c.Exception)
+	}
+	if c.Data()["path"] != "/users" || gotAuth != "Bearer token" {
+		t.Errorf("data = %v, authorization = %q", c.Data(), gotAuth)
+	}
+}
+
+func TestSend_Concurrent(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"code":0,"data":{"id":1}}`)
+	c := New[H]().SetRequest(MethodGet, srv.URL+"/users/1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Send()
+		}()
+	}
+	wg.Wait()
+
+	if !isEmpty(c.Exception) {
+		t.Errorf("unexpected exception: %+v", c.Exception)
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	c := New[any]().SetRequest(MethodGet, "https://example.com/ws").SetBearerAuth("secret").SetHeader("X-Org", "acme")
+	req, err := c.BuildRequest()
+	if err != nil {
+		t.Fatalf("BuildRequest() error = %v", err)
+	}
+	if req.URL.String() != "https://example.com/ws" || req.Header.Get(HeaderAuthorizationKey) != "Bearer secret" ||
+		req.Header.Get("X-Org") != "acme" || c.Context.HttpClient == nil {
+		t.Errorf("request = %+v, want the url and the headers of the client", req)
+	}
+
+	c = New[any]().SetRequest(MethodGet, "https://example.com/users/:id")
+	if _, err = c.BuildRequest(); err == nil || c.Exception.Kind != KindRequest {
+		t.Errorf("err = %v, exception = %+v, want an unfilled path parameter error", err, c.Exception)
+	}
+
+	if cfg := New[any](WithSkipTLS[any](true)).TLSConfig(); !cfg.InsecureSkipVerify {
+		t.Errorf("TLSConfig() = %+v, want SkipTLS applied", cfg)
+	}
+}
+
+func TestSetEmptyJSONBody(t *testing.T) {
+	var gotBody, gotType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotType = string(b), r.Header.Get(HeaderContentTypeKey)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodPost, srv.URL+"/jobs/7/cancel").Send()
+	if !isEmpty(c.Exception) || gotBody != "" {
+		t.Fatalf("body = %q, exception = %+v, want no body by default", gotBody, c.Exception)
+	}
+
+	c = New[H]().SetRequest(MethodPost, srv.URL+"/jobs/7/cancel").SetEmptyJSONBody().Send()
+	if !isEmpty(c.Exception) || gotBody != "{}" || gotType != JsonContentType {
+		t.Errorf("body = %q, Content-Type = %q, exception = %+v, want an empty json object", gotBody, gotType, c.Exception)
+	}
+}
+
+func TestSetPayload_ZeroValue(t *testing.T) {
+	type filter struct {
+		Limit  int  `json:"limit"`
+		Active bool `json:"active"`
+	}
+
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		payload any
+		want    string
+	}{
+		{filter{}, `{"limit":0,"active":false}`},
+		{0, `0`},
+		{nil, ``},
+		{(*filter)(nil), ``},
+		{H(nil), ``},
+	}
+	for _, tt := range tests {
+		c := New[H]().SetRequest(MethodPost, srv.URL).SetPayload(tt.payload).Send()
+		if !isEmpty(c.Exception) || gotBody != tt.want {
+			t.Errorf("SetPayload(%#v): body = %q, want %q, exception = %+v", tt.payload, gotBody, tt.want, c.Exception)
+		}
+	}
+
+	if POST[H](srv.URL, nil, filter{}); gotBody != `{"limit":0,"active":false}` {
+		t.Errorf("POST body = %q, want the zero struct", gotBody)
+	}
+}
+
+func TestExpectContentType(t *testing.T) {
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContentTypeKey, contentType)
+		if strings.HasPrefix(contentType, "text/html") {
+			_, _ = w.Write([]byte(`<html><body>Please log in</body></html>`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	contentType = "Application/JSON; charset=utf-8"
+	if c := New[H]().SetRequest(MethodGet, srv.URL).ExpectContentType(JsonContentType).Send(); !isEmpty(c.Exception) {
+		t.Fatalf("exception = %+v, want the content type to match", c.Exception)
+	}
+
+	contentType = "text/html; charset=utf-8"
+	c := New[H]().SetRequest(MethodGet, srv.URL).ExpectContentType(JsonContentType).Send()
+	if c.Exception.Kind != KindDecode || !errors.Is(c.Exception.PanicError, ErrUnexpectedContentType) ||
+		!strings.Contains(c.Exception.PanicError.Error(), "Please log in") {
+		t.Errorf("exception = %+v, want an unexpected content type", c.Exception)
+	}
+}
+
+func TestSetUploadProgress(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	var sent, total int64
+	var calls int
+	progress := func(s, t int64) { sent, total, calls = s, t, calls+1 }
+
+	body := strings.Repeat("x", 1<<20)
+	c := New[H](WithRetry[H](1, time.Millisecond)).
+		SetRequest(MethodPut, srv.URL+"/upload").
+		SetPayload(body).
+		SetUploadProgress(progress).
+		Send()
+	want := int64(len(body) + 2) // the json quotes
+	if !isEmpty(c.Exception) || sent != want || total != want || calls < 2 {
+		t.Fatalf("sent = %d, total = %d, calls = %d, exception = %+v", sent, total, calls, c.Exception)
+	}
+
+	attempts = 1
+	c = New[H]().
+		SetRequest(MethodPut, srv.URL+"/upload").
+		SetBodyReader(io.MultiReader(strings.NewReader(body)), "", -1).
+		SetUploadProgress(progress).
+		Send()
+	if !isEmpty(c.Exception) || sent != int64(len(body)) || total != -1 {
+		t.Errorf("sent = %d, total = %d, exception = %+v, want an unknown total", sent, total, c.Exception)
+	}
+}
+
+func TestSetIfNoneMatch(t *testing.T) {
+	const etag = `"v1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderIfNoneMatchKey) == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set(HeaderETagKey, etag)
+		_, _ = w.Write([]byte(`{"code":0,"data":{"name":"gloria"}}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().SetRequest(MethodGet, srv.URL).Send()
+	if !isEmpty(c.Exception) || c.NotModified() || c.ETag() != etag || c.Data()["name"] != "gloria" {
+		t.Fatalf("etag = %q, data = %v, exception = %+v", c.ETag(), c.Data(), c.Exception)
+	}
+
+	c = New[H]().SetRequest(MethodGet, srv.URL).SetIfNoneMatch(c.ETag()).Send()
+	if !isEmpty(c.Exception) || !c.NotModified() || c.Data() != nil {
+		t.Errorf("not modified = %v, data = %v, exception = %+v", c.NotModified(), c.Data(), c.Exception)
+	}
+}
+
+// newConnCountingServer starts a local server replying an empty envelope, which counts the connections
+// it accepts.
+func newConnCountingServer(t testing.TB, conns *int64) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(conns, 1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestSend_ReuseTransport(t *testing.T) {
+	var conns int64
+	srv := newConnCountingServer(t, &conns)
+
+	c := New[H]()
+	for i := 0; i < 3; i++ {
+		if c.SetRequest(MethodGet, srv.URL+"/ping").Send(); !isEmpty(c.Exception) {
+			t.Fatalf("unexpected exception: %+v", c.Exception)
+		}
+	}
+	if n := atomic.LoadInt64(&conns); n != 1 || !c.ConnectionReused() {
+		t.Errorf("connections = %d, reused = %t, want 1 (the keep-alive connection is reused)", n, c.ConnectionReused())
+	}
+
+	tr := c.Config.transport
+	if c.Optional(WithSkipTLS[H](true)).SetRequest(MethodGet, srv.URL+"/ping").Send(); c.Config.transport == tr {
+		t.Error("the transport should be rebuilt when its settings change")
+	}
+}
+
+func BenchmarkSend_KeepAlive(b *testing.B) {
+	var conns int64
+	srv := newConnCountingServer(b, &conns)
+
+	c := New[H]()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.SetRequest(MethodGet, srv.URL+"/ping").Send()
+	}
+	b.ReportMetric(float64(atomic.LoadInt64(&conns))/float64(b.N), "conns/op")
+}
+
+func TestClient_SetGet(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"code":0}`)
+
+	var took time.Duration
+	c := New[H]()
+	c.UsePreHooks(func(c *Client[H]) error {
+		c.Set("start", time.Now())
+		return nil
+	})
+	c.UsePostHooks(func(c *Client[H]) error {
+		start, ok := c.Get("start")
+		if !ok {
+			return errors.New("no start time")
+		}
+		took = time.Since(start.(time.Time))
+		return nil
+	})
+
+	if c.SetRequest(MethodGet, srv.URL).Send(); !isEmpty(c.Exception) || took <= 0 {
+		t.Fatalf("took = %v, exception = %+v", took, c.Exception)
+	}
+	if _, ok := c.Reset().Get("start"); ok {
+		t.Error("the stored values should be cleared by Reset")
+	}
+}
+
+func TestClient_NamedHooks(t *testing.T) {
+	var gotAccept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAccept = r.Header.Get(HeaderAcceptKey)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := Default[H]()
+	c.RemovePreHook(HookDefaultHeaders)
+	if c.SetRequest(MethodGet, srv.URL).Send(); !isEmpty(c.Exception) || gotAccept != "" {
+		t.Fatalf("Accept = %q, want no default header (exception %+v)", gotAccept, c.Exception)
+	}
+
+	var order []string
+	record := func(name string) beforeRequest[H] {
+		return func(*Client[H]) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	c = New[H]()
+	c.UsePreHookNamed("a", record("a"))
+	c.UsePreHooks(record("b"))
+	c.UsePreHookNamed("a", record("a2"))
+	c.UsePreHookNamed("c", record("c"))
+	c.RemovePreHook("c")
+	c.RemovePreHook("")
+	c.SetRequest(MethodGet, srv.URL).Send()
+	if strings.Join(order, ",") != "a2,b" {
+		t.Errorf("hooks ran as %v, want [a2 b]", order)
+	}
+}
+
+func TestSend_APIVersion(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().Optional(WithAPIVersion[H]("v1"))
+	if c.SetRequest(MethodGet, srv.URL+"/users/7").Send(); gotPath != "/v1/users/7" {
+		t.Errorf("path = %q, want %q", gotPath, "/v1/users/7")
+	}
+	if c.SetRequest(MethodGet, srv.URL+"/users/7").SetAPIVersion("v2").Send(); gotPath != "/v2/users/7" {
+		t.Errorf("path = %q, want %q", gotPath, "/v2/users/7")
+	}
+
+	c = New[H]().Optional(WithBaseURL[H](srv.URL+"/api/:version"), WithAPIVersion[H]("v1"))
+	if c.SetRequest(MethodGet, "/users/:id").SetPathParam("id", "7").Send(); gotPath != "/api/v1/users/7" {
+		t.Errorf("path = %q, want %q (exception %+v)", gotPath, "/api/v1/users/7", c.Exception)
+	}
+}
+
+func TestSetHeader_CanonicalKeys(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 10; i++ {
+		c := Default[H]().
+			Optional(WithDefaultHeaders[H](H{"x-org": "default"})).
+			SetHeader("x-trace", "a").
+			SetHeader("X-Trace", "b").
+			SetHeaders(H{"content-type": "application/vnd.api+json", "X-ORG": "acme"}).
+			SetRequest(MethodGet, srv.URL).
+			Send()
+		if !isEmpty(c.Exception) {
+			t.Fatalf("unexpected exception: %+v", c.Exception)
+		}
+		if v := got.Values("X-Trace"); len(v) != 1 || v[0] != "b" {
+			t.Fatalf("X-Trace = %v, want [b]", v)
+		}
+		if v := got.Values(HeaderContentTypeKey); len(v) != 1 || v[0] != "application/vnd.api+json" {
+			t.Fatalf("Content-Type = %v, want the custom one only", v)
+		}
+		if v := got.Values("X-Org"); len(v) != 1 || v[0] != "acme" {
+			t.Fatalf("X-Org = %v, want [acme]", v)
+		}
+	}
+}
+
+func TestSetHeader_MergedWithRequestHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().
+		Optional(WithDefaultHeaders[H](H{"X-Org": "acme"})).
+		SetHeader("X-Trace", "a").
+		SetUserAgent("gloria-test").
+		SetBearerAuth("secret").
+		SetContentType(JsonContentType).
+		SetRequest(MethodPost, srv.URL).
+		SetJsonPayload(H{"name": "gloria"}).
+		Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	want := map[string]string{
+		"X-Org":              "acme",
+		"X-Trace":            "a",
+		"User-Agent":         "gloria-test",
+		"Authorization":      "Bearer secret",
+		HeaderContentTypeKey: JsonContentType,
+	}
+	for k, v := range want {
+		if got.Get(k) != v {
+			t.Fatalf("%s = %q, want %q", k, got.Get(k), v)
+		}
+	}
+}
+
+func TestSetContext(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"code":0}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := New[H]().SetContext(ctx).SetRequest(MethodGet, srv.URL).Send()
+	if c.Exception.Kind != KindCanceled {
+		t.Fatalf("kind = %q, want %q", c.Exception.Kind, KindCanceled)
+	}
+
+	// the context of a single call takes precedence
+	c.Reset().SendWith(WithRequestContext(context.Background()))
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+}
+
+func TestDo(t *testing.T) {
+	var gotMethod, gotBody string
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotMethod, gotBody, gotHeader = r.Method, string(b), r.Header.Clone()
+		_, _ = w.Write([]byte(`{"code":0,"data":{"id":7}}`))
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(MethodPut, srv.URL+"/files/7?v=2", strings.NewReader("raw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Custom", "prebuilt")
+
+	c := New[H]().SetHeader("X-Builder", "ignored").SetRequest(MethodGet, "http://example.invalid/other")
+	var okCalled bool
+	c.Do(req).Then(func(data H) {
+		okCalled = data["id"] == float64(7)
+	})
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if !okCalled {
+		t.Errorf("data = %v, want the decoded response", c.Data())
+	}
+	if gotMethod != MethodPut || gotBody != "raw" {
+		t.Errorf("request = %s %q, want PUT \"raw\"", gotMethod, gotBody)
+	}
+	if gotHeader.Get("X-Custom") != "prebuilt" || gotHeader.Get("X-Builder") != "" {
+		t.Errorf("headers = %v, want the prebuilt ones only", gotHeader)
+	}
+	if c.Meta.Method != MethodPut || c.Meta.Url != srv.URL+"/files/7?v=2" {
+		t.Errorf("meta = %s %s, want the prebuilt request", c.Meta.Method, c.Meta.Url)
+	}
+
+	if c = New[H]().Do(nil); c.Exception.Kind != KindRequest {
+		t.Errorf("nil request kind = %q, want %q", c.Exception.Kind, KindRequest)
+	}
+}
+
+func TestSetBodyReader_Resend(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	// a seekable reader, such as the one of SetFormStruct, is rewound
+	type form struct {
+		A string `form:"a"`
+	}
+	c := New[H]().SetRequest(MethodPost, srv.URL).SetFormStruct(form{A: "hello"})
+	c.Send()
+	c.Send()
+	if !isEmpty(c.Exception) || len(bodies) != 2 || bodies[0] != "a=hello" || bodies[1] != "a=hello" {
+		t.Fatalf("bodies = %q, exception = %+v, want the form twice", bodies, c.Exception)
+	}
+
+	// a one-shot reader cannot be sent twice
+	bodies = nil
+	c = New[H]().SetRequest(MethodPost, srv.URL).SetBodyReader(io.MultiReader(strings.NewReader("once")), "text/plain", -1)
+	c.Send()
+	c.Send()
+	if len(bodies) != 1 || bodies[0] != "once" || c.Exception.Kind != KindRequest || !errors.Is(c.Exception, ErrBodyConsumed) {
+		t.Fatalf("bodies = %q, exception = %+v, want ErrBodyConsumed", bodies, c.Exception)
+	}
+}
@@ -0,0 +1,14 @@
+module github.com/pokeyaro/gloria/codec/jsoniter
+
+go 1.20
+
+require (
+	github.com/json-iterator/go v1.1.12
+	github.com/pokeyaro/gloria v1.1.0
+)
+
+require (
+	github.com/goccy/go-json v0.10.2 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+)
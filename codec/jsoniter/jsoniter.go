@@ -0,0 +1,48 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package jsoniter provides a gloria.JSONLibrary implemented by json-iterator, in a separate module so
+// that gloria does not force the dependency.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().RegisterJsonLib(jsoniter.Library{})
+package jsoniter
+
+import (
+	jsoniterlib "github.com/json-iterator/go"
+
+	"github.com/pokeyaro/gloria"
+)
+
+// api is the json-iterator configuration compatible with encoding/json.
+var api = jsoniterlib.ConfigCompatibleWithStandardLibrary
+
+// numberAPI is api decoding the numbers of the interface{} values into json.Number.
+var numberAPI = jsoniterlib.Config{
+	EscapeHTML:             true,
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+	UseNumber:              true,
+}.Froze()
+
+// Library is the json-iterator implementation of gloria.JSONLibrary.
+type Library struct{}
+
+var (
+	_ gloria.JSONLibrary       = Library{}
+	_ gloria.NumberJSONLibrary = Library{}
+)
+
+func (l Library) Marshal(v interface{}) ([]byte, error) {
+	return api.Marshal(v)
+}
+
+func (l Library) Unmarshal(data []byte, v interface{}) error {
+	return api.Unmarshal(data, v)
+}
+
+func (l Library) UnmarshalUseNumber(data []byte, v interface{}) error {
+	return numberAPI.Unmarshal(data, v)
+}
@@ -0,0 +1,18 @@
+module github.com/pokeyaro/gloria/codec/sonic
+
+go 1.20
+
+require (
+	github.com/bytedance/sonic v1.15.0
+	github.com/pokeyaro/gloria v1.1.0
+)
+
+require (
+	github.com/bytedance/gopkg v0.1.3 // indirect
+	github.com/bytedance/sonic/loader v0.5.0 // indirect
+	github.com/cloudwego/base64x v0.1.6 // indirect
+	github.com/goccy/go-json v0.10.2 // indirect
+	github.com/klauspost/cpuid/v2 v2.2.9 // indirect
+	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
+	golang.org/x/sys v0.22.0 // indirect
+)
@@ -0,0 +1,50 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package sonic provides a gloria.JSONLibrary implemented by bytedance/sonic, in a separate module so
+// that gloria does not force the dependency.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().RegisterJsonLib(sonic.Library{})
+package sonic
+
+import (
+	soniclib "github.com/bytedance/sonic"
+
+	"github.com/pokeyaro/gloria"
+)
+
+// api is the sonic configuration compatible with encoding/json.
+var api = soniclib.ConfigStd
+
+// numberAPI is api decoding the numbers of the interface{} values into json.Number.
+var numberAPI = soniclib.Config{
+	EscapeHTML:       true,
+	SortMapKeys:      true,
+	CompactMarshaler: true,
+	CopyString:       true,
+	ValidateString:   true,
+	UseNumber:        true,
+}.Froze()
+
+// Library is the sonic implementation of gloria.JSONLibrary.
+type Library struct{}
+
+var (
+	_ gloria.JSONLibrary       = Library{}
+	_ gloria.NumberJSONLibrary = Library{}
+)
+
+func (l Library) Marshal(v interface{}) ([]byte, error) {
+	return api.Marshal(v)
+}
+
+func (l Library) Unmarshal(data []byte, v interface{}) error {
+	return api.Unmarshal(data, v)
+}
+
+func (l Library) UnmarshalUseNumber(data []byte, v interface{}) error {
+	return numberAPI.Unmarshal(data, v)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package sonic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pokeyaro/gloria"
+)
+
+func TestLibrary(t *testing.T) {
+	type user struct {
+		ID   int      `json:"id"`
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	in := user{ID: 1, Name: "gloria", Tags: []string{"a", "b"}}
+
+	data, err := Library{}.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want, _ := json.Marshal(in); string(data) != string(want) {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var out user
+	if err = (Library{}).Unmarshal(data, &out); err != nil || out.Name != in.Name || len(out.Tags) != len(in.Tags) {
+		t.Fatalf("Unmarshal() = %+v, %v", out, err)
+	}
+}
+
+func TestLibrary_UnmarshalUseNumber(t *testing.T) {
+	var out map[string]interface{}
+	if err := (Library{}).UnmarshalUseNumber([]byte(`{"id":9007199254740993}`), &out); err != nil {
+		t.Fatalf("UnmarshalUseNumber() error = %v", err)
+	}
+	if n, ok := out["id"].(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Errorf("id = %#v, want json.Number 9007199254740993", out["id"])
+	}
+}
+
+type benchPayload struct {
+	ID    int               `json:"id"`
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]string `json:"attrs"`
+	Items []benchItem       `json:"items"`
+}
+
+type benchItem struct {
+	SKU   string  `json:"sku"`
+	Price float64 `json:"price"`
+}
+
+func benchmarkLibrary(b *testing.B, lib gloria.JSONLibrary) {
+	in := benchPayload{ID: 1, Name: "gloria", Tags: []string{"a", "b"}, Attrs: map[string]string{"k": "v"}}
+	for i := 0; i < 100; i++ {
+		in.Items = append(in.Items, benchItem{SKU: "sku", Price: float64(i) / 3})
+	}
+	data, _ := lib.Marshal(in)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out benchPayload
+		if _, err := lib.Marshal(in); err != nil {
+			b.Fatal(err)
+		}
+		if err := lib.Unmarshal(data, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNativeJSONLibrary(b *testing.B) { benchmarkLibrary(b, gloria.NativeJSONLibrary{}) }
+func BenchmarkGoJSONLibrary(b *testing.B)     { benchmarkLibrary(b, gloria.GoJSONLibrary{}) }
+func BenchmarkSonicLibrary(b *testing.B)      { benchmarkLibrary(b, Library{}) }
@@ -0,0 +1,85 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToCurl renders the request of the client instance as an equivalent curl command, with its method,
+// url, headers (including the authorization and the cookies) and body, which is handy to share the
+// reproduction of an issue. The request is built by BuildRequest, so it is not sent, and an empty string
+// is returned if it cannot be built. A body which cannot be rendered (a streamed or binary body) is read
+// from the standard input of curl instead.
+//
+// Example usage:
+//
+//	fmt.Println(client.SetRequest(gloria.MethodPost, "https://example.com/users").SetPayload(user).ToCurl())
+func (c *Client[T]) ToCurl() string {
+	req, err := c.BuildRequest()
+	if err != nil {
+		return ""
+	}
+
+	args := []string{"curl"}
+	if req.Method != MethodGet {
+		args = append(args, "-X", req.Method)
+	}
+	args = append(args, shellQuote(req.URL.String()))
+
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range req.Header[k] {
+			args = append(args, "-H", shellQuote(k+": "+v))
+		}
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if body, ok := curlBody(req); ok {
+			args = append(args, "--data-raw", shellQuote(body))
+		} else {
+			args = append(args, "--data-binary", "@-")
+		}
+	}
+
+	if c.Config.SkipTLS {
+		args = append(args, "--insecure")
+	}
+
+	return strings.Join(args, " ")
+}
+
+// curlBody returns the body of req as a string, it returns false if the body cannot be read without
+// consuming the request (a streamed body) or if it is binary (such as a compressed body).
+func curlBody(req *http.Request) (string, bool) {
+	if req.GetBody == nil {
+		return "", false
+	}
+
+	rc, err := req.GetBody()
+	if err != nil {
+		return "", false
+	}
+	defer rc.Close()
+
+	bs, err := io.ReadAll(rc)
+	if err != nil || !utf8.Valid(bs) || !isEmpty(req.Header.Get(HeaderContentEncodingKey)) {
+		return "", false
+	}
+	return string(bs), true
+}
+
+// shellQuote quotes s for a POSIX shell with single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestToCurl(t *testing.T) {
+	c := New[any]().
+		SetRequest(MethodPost, "https://example.com/users?page=1").
+		SetBearerAuth("secret").
+		SetHeader("X-Note", "it's me").
+		SetPayload(H{"name": "gloria"})
+	got := c.ToCurl()
+	for _, want := range []string{
+		`curl -X POST 'https://example.com/users?page=1'`,
+		`-H 'Authorization: Bearer secret'`,
+		`-H 'X-Note: it'\''s me'`,
+		`--data-raw '{"name":"gloria"}'`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ToCurl() = %s, want it to contain %s", got, want)
+		}
+	}
+
+	got = New[any]().SetRequest(MethodGet, "https://example.com/ping").
+		SetBodyReader(io.MultiReader(strings.NewReader("stream")), "", -1).ToCurl()
+	if !strings.HasPrefix(got, "curl 'https://example.com/ping'") || !strings.HasSuffix(got, "--data-binary @-") {
+		t.Errorf("ToCurl() = %s, want a GET reading the streamed body from stdin", got)
+	}
+
+	if got = New[any]().SetRequest(MethodGet, "https://example.com/users/:id").ToCurl(); got != "" {
+		t.Errorf("ToCurl() = %s, want empty for an invalid request", got)
+	}
+}
+
+func TestToCurl_IndentedJSON(t *testing.T) {
+	got := New[any]().
+		Optional(WithIndentedJSON[any]("", "  ")).
+		SetRequest(MethodPost, "https://example.com/users").
+		SetPayload(H{"name": "gloria"}).
+		ToCurl()
+	if want := "--data-raw '{\n  \"name\": \"gloria\"\n}'"; !strings.HasSuffix(got, want) {
+		t.Errorf("ToCurl() = %s, want it to end with %s", got, want)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnvelopeKeys defines the json keys of the rest mode envelope.
+type EnvelopeKeys struct {
+	Code string // key of the business code
+	Msg  string // key of the business message
+	Data string // key of the data
+}
+
+// defaultEnvelopeKeys matches the json tags of RESTFulResp.
+var defaultEnvelopeKeys = EnvelopeKeys{
+	Code: "code",
+	Msg:  "msg",
+	Data: "data",
+}
+
+// KeyCase defines how the keys of a json response body are rewritten before decoding.
+type KeyCase int
+
+// Key cases
+const (
+	// KeyCaseNone keeps the keys unchanged, it is the default
+	KeyCaseNone KeyCase = iota
+
+	// KeySnakeToCamel rewrites snake_case keys to camelCase, such as "user_name" to "userName"
+	KeySnakeToCamel
+
+	// KeyCamelToSnake rewrites camelCase keys to snake_case, such as "userName" to "user_name"
+	KeyCamelToSnake
+)
+
+// decodeBody decodes the response body into the Result of the client instance.
+// In http mode the whole body is decoded as the data, and in rest mode the body is decoded as an
+// envelope according to Config.EnvelopeKeys. When Config.SuccessFunc is set, a non-numeric code
+// is tolerated and left out of Result.Code. When Config.DataPath is set, the data is the subtree
+// at that path of the whole body instead. The keys are rewritten beforehand according to
+// Config.KeyCase. In rest mode, a GraphQL response (see SetGraphQL) is decoded as a data/errors
+// envelope instead. A non-json body (such as text/plain or text/csv) is copied as is into the data
+// when T is a string or a []byte.
+//
+// This internal function is called by the Send method.
+func (c *Client[T]) decodeBody(bs []byte) error {
+	if resp := c.Context.Response.R; resp != nil && !isStructuredContentType(resp.Header.Get(HeaderContentTypeKey)) {
+		switch data := any(&c.Result.Data).(type) {
+		case *string:
+			*data = string(bs)
+			return nil
+		case *[]byte:
+			*data = append([]byte(nil), bs...)
+			return nil
+		}
+	}
+
+	if c.Config.KeyCase != KeyCaseNone {
+		transformed, err := transformKeys(bs, c.Config.KeyCase)
+		if err != nil {
+			return err
+		}
+		bs = transformed
+	}
+
+	if !c.Config.IsRestMode {
+		return c.decodeData(bs)
+	}
+	if c.graphql {
+		return c.decodeGraphQL(bs)
+	}
+
+	keys := c.Config.EnvelopeKeys
+	if (keys == defaultEnvelopeKeys || keys == (EnvelopeKeys{})) && c.Config.SuccessFunc == nil &&
+		isEmptyString(c.Config.DataPath) {
+		return c.jsonLib().Unmarshal(bs, &c.Result)
+	}
+
+	if keys == (EnvelopeKeys{}) {
+		keys = defaultEnvelopeKeys
+	}
+
+	// Struct tags are static, so the custom keys are extracted from the raw envelope
+	var envelope map[string]json.RawMessage
+	if err := c.Config.JSONLoader.Unmarshal(bs, &envelope); err != nil {
+		return err
+	}
+	if raw, ok := envelope[keys.Code]; ok {
+		// A success function judges the raw body, so the code may be non-numeric
+		if err := c.Config.JSONLoader.Unmarshal(raw, &c.Result.Code); err != nil && c.Config.SuccessFunc == nil {
+			return err
+		}
+	}
+	if raw, ok := envelope[keys.Msg]; ok {
+		if err := c.Config.JSONLoader.Unmarshal(raw, &c.Result.Msg); err != nil {
+			return err
+		}
+	}
+	if !isEmptyString(c.Config.DataPath) {
+		return c.decodeData(bs)
+	}
+	if raw, ok := envelope[keys.Data]; ok {
+		if err := c.jsonLib().Unmarshal(raw, &c.Result.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// jsonLib returns the json library which decodes the values of the response, preserving the numbers
+// if Config.UseNumber is set.
+func (c *Client[T]) jsonLib() JSONLibrary {
+	if c.Config.UseNumber {
+		return numberLibrary{c.Config.JSONLoader}
+	}
+	return c.Config.JSONLoader
+}
+
+// decodeData decodes the body, or its subtree at Config.DataPath, into the data of the Result.
+func (c *Client[T]) decodeData(bs []byte) error {
+	if !isEmptyString(c.Config.DataPath) {
+		raw, err := extractPath(c.Config.JSONLoader, bs, c.Config.DataPath)
+		if err != nil {
+			return err
+		}
+		bs = raw
+	}
+
+	return c.jsonLib().Unmarshal(bs, &c.Result.Data)
+}
+
+// rawData returns the raw json of the data of the body bs, in the same way as decodeBody locates it.
+// It returns nil if the envelope has no data.
+func (c *Client[T]) rawData(bs []byte) (json.RawMessage, error) {
+	if c.Config.KeyCase != KeyCaseNone {
+		transformed, err := transformKeys(bs, c.Config.KeyCase)
+		if err != nil {
+			return nil, err
+		}
+		bs = transformed
+	}
+
+	if !isEmptyString(c.Config.DataPath) {
+		return extractPath(c.Config.JSONLoader, bs, c.Config.DataPath)
+	}
+	if !c.Config.IsRestMode {
+		return bs, nil
+	}
+
+	keys := c.Config.EnvelopeKeys
+	if keys == (EnvelopeKeys{}) {
+		keys = defaultEnvelopeKeys
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := c.Config.JSONLoader.Unmarshal(bs, &envelope); err != nil {
+		return nil, err
+	}
+
+	return envelope[keys.Data], nil
+}
+
+// extractPath returns the raw json subtree at the dot-separated path of bs, such as "data.items.0".
+// A numeric segment indexes an array, other segments are object keys.
+func extractPath(lib JSONLibrary, bs []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(bs)
+	for _, key := range strings.Split(path, signDot) {
+		if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			idx, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, fmt.Errorf("data path %q: %q is not an array index", path, key)
+			}
+			var arr []json.RawMessage
+			if err = lib.Unmarshal(raw, &arr); err != nil {
+				return nil, err
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, fmt.Errorf("data path %q: index %d out of range", path, idx)
+			}
+			raw = arr[idx]
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		if err := lib.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("data path %q: %q is not an object key: %w", path, key, err)
+		}
+		next, ok := obj[key]
+		if !ok {
+			return nil, fmt.Errorf("data path %q: key %q not found", path, key)
+		}
+		raw = next
+	}
+
+	return raw, nil
+}
+
+// transformKeys rewrites the object keys of the json document bs according to mode, by streaming
+// its tokens, so that values (including numbers) are kept as is.
+func transformKeys(bs []byte, mode KeyCase) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	var objects []bool // open containers, true for an object
+	var counts []int   // tokens written in each open container
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			objects, counts = objects[:len(objects)-1], counts[:len(counts)-1]
+			buf.WriteByte(byte(d))
+			continue
+		}
+
+		isKey := false
+		if top := len(objects) - 1; top >= 0 {
+			n := counts[top]
+			isKey = objects[top] && n%2 == 0
+			switch {
+			case n > 0 && objects[top] && !isKey:
+				buf.WriteByte(':')
+			case n > 0:
+				buf.WriteByte(',')
+			}
+			counts[top]++
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			buf.WriteByte(byte(v))
+			objects, counts = append(objects, v == '{'), append(counts, 0)
+		case json.Number:
+			buf.WriteString(v.String())
+		case string:
+			if isKey {
+				v = convertKey(v, mode)
+			}
+			b, _ := json.Marshal(v)
+			buf.Write(b)
+		default:
+			b, _ := json.Marshal(v)
+			buf.Write(b)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// convertKey converts a single key according to mode.
+func convertKey(key string, mode KeyCase) string {
+	switch mode {
+	case KeySnakeToCamel:
+		return snakeToCamel(key)
+	case KeyCamelToSnake:
+		return camelToSnake(key)
+	default:
+		return key
+	}
+}
+
+// snakeToCamel converts a snake_case string to camelCase, such as "user_name" to "userName".
+func snakeToCamel(s string) string {
+	var sb strings.Builder
+	upper := false
+	for i, r := range s {
+		switch {
+		case r == '_' && i > 0:
+			upper = true
+		case upper:
+			sb.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// camelToSnake converts a camelCase string to snake_case, such as "userName" to "user_name".
+// An acronym is kept as a single word, such as "userID" to "user_id".
+func camelToSnake(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			acronymEnd := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
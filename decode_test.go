@@ -0,0 +1,290 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSend_EnvelopeKeys(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"errcode":1001,"errmsg":"invalid","result":{"id":1}}`)
+
+	for _, lib := range []JSONLibrary{NativeJSONLibrary{}, GoJSONLibrary{}} {
+		c := New[H]().
+			Optional(WithEnvelopeKeys[H]("errcode", "errmsg", "result"), WithRegisterJsonLibrary[H](lib)).
+			SetRequest(MethodGet, srv.URL).
+			Send()
+		if !isEmpty(c.Exception) {
+			t.Fatalf("unexpected exception: %+v", c.Exception)
+		}
+		if c.Result.Code != 1001 || c.Result.Msg != "invalid" || c.Data()["id"] != float64(1) {
+			t.Errorf("%T: result = %+v", lib, c.Result)
+		}
+	}
+}
+
+func TestSend_SuccessFunc(t *testing.T) {
+	isOk := func(raw []byte) bool {
+		var env struct {
+			Code string `json:"code"`
+		}
+		return json.Unmarshal(raw, &env) == nil && env.Code == "OK"
+	}
+
+	tests := []struct {
+		body string
+		ok   bool
+	}{
+		{`{"code":"OK","msg":"success","data":{"id":1}}`, true},
+		{`{"code":"E1001","msg":"invalid","data":null}`, false},
+	}
+
+	for _, tt := range tests {
+		srv := newTestServer(t, http.StatusOK, tt.body)
+		c := New[H]().
+			Optional(WithSuccessFunc[H](isOk)).
+			SetRequest(MethodGet, srv.URL).
+			Send()
+		data, err := c.Try()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Try() error = %v, want ok %v", tt.body, err, tt.ok)
+		}
+		if tt.ok && data["id"] != float64(1) {
+			t.Errorf("%s: data = %v", tt.body, data)
+		}
+	}
+}
+
+func TestSend_DataPath(t *testing.T) {
+	body := `{"code":0,"msg":"ok","data":{"result":{"items":[{"id":1},{"id":2}]}}}`
+
+	t.Run("http mode", func(t *testing.T) {
+		srv := newTestServer(t, http.StatusOK, body)
+		c := New[[]H]().
+			Optional(WithDataPath[[]H]("data.result.items")).
+			ToggleMode().
+			SetRequest(MethodGet, srv.URL).
+			Send()
+		if !isEmpty(c.Exception) || len(c.Data()) != 2 {
+			t.Fatalf("data = %v, exception %+v", c.Data(), c.Exception)
+		}
+	})
+
+	t.Run("rest mode with index", func(t *testing.T) {
+		srv := newTestServer(t, http.StatusOK, body)
+		c := New[H]().
+			Optional(WithDataPath[H]("data.result.items.1")).
+			SetRequest(MethodGet, srv.URL).
+			Send()
+		if !isEmpty(c.Exception) || c.Data()["id"] != float64(2) || c.Result.Msg != "ok" {
+			t.Fatalf("result = %+v, exception %+v", c.Result, c.Exception)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		srv := newTestServer(t, http.StatusOK, body)
+		c := New[H]().
+			Optional(WithDataPath[H]("data.missing")).
+			SetRequest(MethodGet, srv.URL).
+			Send()
+		if isEmpty(c.Exception) || c.Exception.Kind != KindDecode {
+			t.Fatalf("exception = %+v, want a decode error", c.Exception)
+		}
+	})
+}
+
+func TestTransformKeys(t *testing.T) {
+	in := `{"user_name":"a_b","items":[{"item_id":12345678901234567890},true,null],"nested_obj":{"is_ok":false}}`
+	want := `{"userName":"a_b","items":[{"itemId":12345678901234567890},true,null],"nestedObj":{"isOk":false}}`
+
+	got, err := transformKeys([]byte(in), KeySnakeToCamel)
+	if err != nil || string(got) != want {
+		t.Fatalf("transformKeys() = %s, %v, want %s", got, err, want)
+	}
+
+	back, err := transformKeys(got, KeyCamelToSnake)
+	if err != nil || string(back) != in {
+		t.Fatalf("transformKeys() = %s, %v, want %s", back, err, in)
+	}
+}
+
+func TestCamelToSnake(t *testing.T) {
+	tests := map[string]string{
+		"userName":  "user_name",
+		"userID":    "user_id",
+		"HTTPCode":  "http_code",
+		"page2Size": "page2_size",
+		"plain":     "plain",
+	}
+	for in, want := range tests {
+		if got := camelToSnake(in); got != want {
+			t.Errorf("camelToSnake(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSend_KeyTransform(t *testing.T) {
+	type user struct {
+		UserName string `json:"userName"`
+	}
+
+	srv := newTestServer(t, http.StatusOK, `{"code":0,"msg":"ok","data":{"user_name":"gloria"}}`)
+	c := New[user]().
+		Optional(WithKeyTransform[user](KeySnakeToCamel)).
+		SetRequest(MethodGet, srv.URL).
+		Send()
+	if !isEmpty(c.Exception) || c.Data().UserName != "gloria" {
+		t.Fatalf("data = %+v, exception %+v", c.Data(), c.Exception)
+	}
+}
+
+func TestSend_UseNumber(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"code":0,"msg":"ok","data":{"id":9007199254740993}}`)
+
+	for _, lib := range []JSONLibrary{NativeJSONLibrary{}, GoJSONLibrary{}, numberlessLibrary{}} {
+		c := New[H]().
+			Optional(WithUseNumber[H](true), WithRegisterJsonLibrary[H](lib)).
+			SetRequest(MethodGet, srv.URL).
+			Send()
+		if !isEmpty(c.Exception) || c.Result.Code != 0 {
+			t.Fatalf("%T: result = %+v, exception = %+v", lib, c.Result, c.Exception)
+		}
+		if id, ok := c.Data()["id"].(json.Number); !ok || id.String() != "9007199254740993" {
+			t.Errorf("%T: id = %#v, want json.Number 9007199254740993", lib, c.Data()["id"])
+		}
+	}
+
+	var v H
+	if err := (numberLibrary{NativeJSONLibrary{}}).Unmarshal([]byte(`{"id":1} {}`), &v); err == nil {
+		t.Error("trailing data should be rejected")
+	}
+}
+
+// numberlessLibrary is a json library which does not implement NumberJSONLibrary.
+type numberlessLibrary struct{}
+
+func (numberlessLibrary) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (numberlessLibrary) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+func TestClient_ScanData(t *testing.T) {
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	srv := newTestServer(t, http.StatusOK, `{"errcode":0,"errmsg":"ok","result":{"id":7,"name":"gloria"}}`)
+	c := New[any]().
+		Optional(WithEnvelopeKeys[any]("errcode", "errmsg", "result")).
+		SetRequest(MethodGet, srv.URL).
+		Send()
+
+	var u user
+	if err := c.ScanData(&u); err != nil || u.ID != 7 || u.Name != "gloria" {
+		t.Fatalf("ScanData() = %+v, %v", u, err)
+	}
+	if c.Result.Msg != "ok" {
+		t.Errorf("msg = %q, want %q", c.Result.Msg, "ok")
+	}
+}
+
+func TestSend_PlainText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContentTypeKey, r.URL.Query().Get("type"))
+		_, _ = w.Write([]byte(r.URL.Query().Get("echo")))
+	}))
+	defer srv.Close()
+
+	t.Run("string in rest mode", func(t *testing.T) {
+		c := New[string]().
+			SetRequest(MethodGet, srv.URL).
+			SetQueryParams(H{"type": PlainTextType, "echo": "hello"}).
+			Send()
+		if !isEmpty(c.Exception) || c.Data() != "hello" {
+			t.Fatalf("data = %q, exception %+v", c.Data(), c.Exception)
+		}
+	})
+
+	t.Run("bytes in http mode", func(t *testing.T) {
+		c := New[[]byte]().
+			ToggleMode().
+			SetRequest(MethodGet, srv.URL).
+			SetQueryParams(H{"type": "text/csv", "echo": "a,b\n1,2"}).
+			Send()
+		if !isEmpty(c.Exception) || string(c.Data()) != "a,b\n1,2" {
+			t.Fatalf("data = %q, exception %+v", c.Data(), c.Exception)
+		}
+	})
+
+	t.Run("json string", func(t *testing.T) {
+		c := New[string]().
+			ToggleMode().
+			SetRequest(MethodGet, srv.URL).
+			SetQueryParams(H{"type": JsonContentType, "echo": `"quoted"`}).
+			Send()
+		if !isEmpty(c.Exception) || c.Data() != "quoted" {
+			t.Fatalf("data = %q, exception %+v", c.Data(), c.Exception)
+		}
+	})
+}
+
+func TestSend_BodyTransform(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `"{\"code\":0,\"msg\":\"ok\",\"data\":{\"id\":1}}"`)
+
+	unwrap := func(b []byte) ([]byte, error) {
+		var inner string
+		if err := json.Unmarshal(b, &inner); err != nil {
+			return nil, err
+		}
+		return []byte(inner), nil
+	}
+
+	c := New[H]()
+	c.UseBodyTransform(unwrap)
+	if c.SetRequest(MethodGet, srv.URL).Send(); !isEmpty(c.Exception) || c.Data()["id"] != float64(1) {
+		t.Fatalf("data = %v, exception = %+v", c.Data(), c.Exception)
+	}
+	if c.RawText() != `{"code":0,"msg":"ok","data":{"id":1}}` {
+		t.Errorf("raw text = %q, want the transformed body", c.RawText())
+	}
+
+	c.UseBodyTransform(unwrap) // the body is no longer a json string
+	if c.SetRequest(MethodGet, srv.URL).Send(); c.Exception.Kind != KindDecode {
+		t.Errorf("exception = %+v, want a decode error", c.Exception)
+	}
+}
+
+func TestWithBodyDecryptor(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	sealed := gcm.Seal(nonce, nonce, []byte(`{"code":0,"msg":"ok","data":{"id":1}}`), nil)
+	srv := newTestServer(t, http.StatusOK, string(sealed))
+
+	decrypt := func(b []byte) ([]byte, error) {
+		if len(b) < gcm.NonceSize() {
+			return nil, errors.New("body too short")
+		}
+		return gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	}
+
+	c := New[H]().Optional(WithBodyDecryptor[H](decrypt)).SetRequest(MethodGet, srv.URL).Send()
+	if !isEmpty(c.Exception) || c.Data()["id"] != float64(1) {
+		t.Fatalf("data = %v, exception = %+v", c.Data(), c.Exception)
+	}
+
+	srv = newTestServer(t, http.StatusOK, `{"code":0}`)
+	c = New[H]().Optional(WithBodyDecryptor[H](decrypt)).SetRequest(MethodGet, srv.URL).Send()
+	if c.Exception.Kind != KindDecode || !strings.Contains(c.Exception.PanicError.Error(), "decrypt") {
+		t.Errorf("exception = %+v, want a decryption error", c.Exception)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by FromEnv
+const (
+	EnvBaseURL       = "GLORIA_BASE_URL"       // base url, see WithBaseURL
+	EnvTimeout       = "GLORIA_TIMEOUT"        // timeout of each attempt, see WithTimeout
+	EnvTotalDeadline = "GLORIA_TOTAL_DEADLINE" // deadline over all attempts, see WithTotalDeadline
+	EnvRetryCount    = "GLORIA_RETRY_COUNT"    // number of retries, see WithRetry
+	EnvRetryWait     = "GLORIA_RETRY_WAIT"     // wait duration between the attempts, see WithRetry
+	EnvSkipTLS       = "GLORIA_SKIP_TLS"       // skip the certificate verification, see WithSkipTLS
+	EnvProxy         = "GLORIA_PROXY"          // url of the proxy, see WithProxy
+	EnvDebug         = "GLORIA_DEBUG"          // debug mode, see WithIsDebug
+)
+
+// FromEnv returns a client instance initialized like Default, then configured from the GLORIA_*
+// environment variables which are set (see EnvBaseURL and the others), so that a twelve-factor app
+// configures its clients without code. The explicit options applied afterwards with Optional take
+// precedence over the environment. A duration is written like "15s" or as a number of seconds, and a
+// boolean like "true" or "1". An invalid value is recorded as an Exception, so the request is not sent.
+//
+// Example usage:
+//
+//	// GLORIA_BASE_URL=https://api.example.org GLORIA_TIMEOUT=15s GLORIA_PROXY=http://proxy.internal:3128
+//	c := gloria.FromEnv[User]().Optional(gloria.WithRetry[User](3, time.Second))
+//	c.SetRequest(gloria.MethodGet, "/users/7").Send()
+func FromEnv[T any]() *Client[T] {
+	client := Default[T]()
+
+	if err := applyEnv(client); err != nil {
+		client.failBuild(&Exception{
+			CodeLocation:   fileLocation(1),
+			Kind:           KindRequest,
+			PanicError:     err,
+			OccurrenceTime: time.Now().Unix(),
+		})
+	}
+
+	return client
+}
+
+// applyEnv applies the GLORIA_* environment variables to the client instance, it returns the error of
+// the first invalid value.
+func applyEnv[T any](c *Client[T]) error {
+	var opts []ClientFunc[T]
+
+	if v, ok := lookupEnv(EnvBaseURL); ok {
+		opts = append(opts, WithBaseURL[T](v))
+	}
+	if v, ok := lookupEnv(EnvTimeout); ok {
+		d, err := parseEnvDuration(EnvTimeout, v)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, WithTimeout[T](d))
+	}
+	if v, ok := lookupEnv(EnvTotalDeadline); ok {
+		d, err := parseEnvDuration(EnvTotalDeadline, v)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, WithTotalDeadline[T](d))
+	}
+	if v, ok := lookupEnv(EnvRetryCount); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q: not a number of retries", EnvRetryCount, v)
+		}
+		opts = append(opts, Lambda[T](func(c *Client[T]) { c.Config.RetryCount = n }))
+	}
+	if v, ok := lookupEnv(EnvRetryWait); ok {
+		d, err := parseEnvDuration(EnvRetryWait, v)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, Lambda[T](func(c *Client[T]) { c.Config.RetryWait = d }))
+	}
+	if v, ok := lookupEnv(EnvSkipTLS); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not a boolean", EnvSkipTLS, v)
+		}
+		opts = append(opts, WithSkipTLS[T](b))
+	}
+	if v, ok := lookupEnv(EnvProxy); ok {
+		opts = append(opts, WithProxy[T](v))
+	}
+	if v, ok := lookupEnv(EnvDebug); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not a boolean", EnvDebug, v)
+		}
+		opts = append(opts, WithIsDebug[T](b))
+	}
+
+	c.Optional(opts...)
+	return nil
+}
+
+// lookupEnv returns the trimmed value of the environment variable key, it returns false if the variable
+// is unset or blank.
+func lookupEnv(key string) (string, bool) {
+	v := strings.TrimSpace(os.Getenv(key))
+	return v, !isEmpty(v)
+}
+
+// parseEnvDuration parses the duration value of the environment variable key, such as "15s" or a number
+// of seconds like "15".
+func parseEnvDuration(key, value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid %s %q: not a duration", key, value)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer proxy.Close()
+
+	t.Setenv(EnvBaseURL, "http://api.example.invalid/v1")
+	t.Setenv(EnvTimeout, "15")
+	t.Setenv(EnvRetryWait, "250ms")
+	t.Setenv(EnvSkipTLS, "false")
+	t.Setenv(EnvProxy, proxy.URL)
+
+	c := FromEnv[H]().Optional(WithTimeout[H](TimeoutLong))
+	cfg := c.Config
+	if cfg.BaseURL != "http://api.example.invalid/v1" || cfg.Timeout != TimeoutLong || cfg.RetryWait != 250*time.Millisecond ||
+		cfg.SkipTLS || cfg.Proxy != proxy.URL {
+		t.Fatalf("config = %+v, want the environment, overridden by the explicit options", cfg)
+	}
+
+	c.Config.Logger = nil
+	if c.SetRequest(MethodGet, "/users").Send(); !isEmpty(c.Exception) || proxied != "http://api.example.invalid/v1/users" {
+		t.Errorf("proxied url = %q, exception = %+v", proxied, c.Exception)
+	}
+
+	t.Setenv(EnvTimeout, "soon")
+	if c = FromEnv[H](); c.Exception.Kind != KindRequest || c.Exception.PanicError == nil {
+		t.Errorf("exception = %+v, want the invalid timeout", c.Exception)
+	}
+}
+
+func TestWithProxy_Invalid(t *testing.T) {
+	c := New[H](WithProxy[H]("proxy.internal")).SetRequest(MethodGet, "http://example.com").Send()
+	if c.Exception.Kind != KindRequest {
+		t.Errorf("exception = %+v, want an invalid proxy url", c.Exception)
+	}
+}
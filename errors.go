@@ -0,0 +1,217 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf8"
+)
+
+// bodySnippetSize is the maximum number of bytes of a response body quoted in a failure reason.
+const bodySnippetSize = 256
+
+// ErrorKind classifies the source of an Exception, so that handlers (such as Catch) can switch
+// on it to decide whether to retry or alert.
+type ErrorKind string
+
+// Error kinds
+const (
+	// KindRequest represents an error while building the request, such as an invalid url
+	// or a failed request hook
+	KindRequest ErrorKind = "REQUEST"
+
+	// KindNetwork represents a network error, such as a dns or dial failure
+	KindNetwork ErrorKind = "NETWORK"
+
+	// KindTimeout represents a request which timed out
+	KindTimeout ErrorKind = "TIMEOUT"
+
+	// KindCanceled represents a request whose context was canceled, which should not be retried
+	KindCanceled ErrorKind = "CANCELED"
+
+	// KindResponse represents a failed response hook
+	KindResponse ErrorKind = "RESPONSE"
+
+	// KindDecode represents a response body which cannot be decoded
+	KindDecode ErrorKind = "DECODE"
+
+	// KindStatus represents an unexpected http status
+	KindStatus ErrorKind = "STATUS"
+
+	// KindBusiness represents a failing business code in rest mode
+	KindBusiness ErrorKind = "BUSINESS"
+)
+
+// ErrCertNotPinned is the error of a connection to a server whose certificate is not pinned,
+// see WithCertPinning.
+var ErrCertNotPinned = errors.New("server certificate is not pinned")
+
+// ErrRedirectLoop is the error of a request redirected to a url it already visited, see WithMaxRedirects.
+var ErrRedirectLoop = errors.New("redirect loop")
+
+// ErrTooManyRedirects is the error of a request redirected more times than allowed, see WithMaxRedirects.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// ErrBodyConsumed is the error of a request whose body reader, which cannot be rewound, was consumed
+// by a previous request, see SetBodyReader.
+var ErrBodyConsumed = errors.New("body reader consumed by a previous request")
+
+// ErrUnexpectedContentType is the error of a response whose Content-Type does not match the expected
+// one, see ExpectContentType.
+var ErrUnexpectedContentType = errors.New("unexpected response content type")
+
+// StatusError is the error recorded when the server responds with an unexpected HTTP status.
+// It carries the status code, the status text and the raw response body, so that handlers
+// can branch on the HTTP status without digging into Context.Response.R.
+type StatusError struct {
+	StatusCode int    // http response status code, such as 404
+	Status     string // http response status text, such as "404 Not Found"
+	Body       []byte // raw response body
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected http status: %s", e.Status)
+}
+
+// BusinessError is the error of a response whose business code does not match the success code
+// in rest mode.
+type BusinessError struct {
+	Code int    // business error code
+	Msg  string // business error message
+}
+
+// Error implements the error interface.
+func (e *BusinessError) Error() string {
+	return fmt.Sprintf("business error code %d: %s", e.Code, e.Msg)
+}
+
+// APIError is an entry of the errors array of a response body, such as the validation errors of a
+// batch request or the errors of a GraphQL response, see WithErrorsKey and Exception.Errors.
+type APIError struct {
+	Message string // "message" (or "msg") of the entry, or the entry itself if it is a string
+	Code    string // "code" of the entry, a numeric code is formatted as a string
+	Field   string // "field" of the entry, or its "path" joined with dots
+	Raw     H      // the whole entry, if it is an object
+}
+
+// Error implements the error interface.
+func (e APIError) Error() string {
+	if isEmptyString(e.Field) {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// parseAPIErrors decodes the raw errors array of a response body, it returns nil if raw is not an array.
+func parseAPIErrors(lib JSONLibrary, raw []byte) []APIError {
+	var entries []json.RawMessage
+	if err := lib.Unmarshal(raw, &entries); err != nil {
+		return nil
+	}
+
+	var apiErrs []APIError
+	for _, entry := range entries {
+		var msg string
+		if err := lib.Unmarshal(entry, &msg); err == nil {
+			apiErrs = append(apiErrs, APIError{Message: msg})
+			continue
+		}
+
+		var obj H
+		if err := lib.Unmarshal(entry, &obj); err != nil || obj == nil {
+			continue
+		}
+		apiErr := APIError{Raw: obj}
+		for _, key := range []string{"message", "msg"} {
+			if v, ok := obj[key].(string); ok {
+				apiErr.Message = v
+				break
+			}
+		}
+		if v, ok := obj["code"]; ok && v != nil {
+			apiErr.Code = fmt.Sprint(v)
+		}
+		if v, ok := obj["field"].(string); ok {
+			apiErr.Field = v
+		} else if path, ok := obj["path"].([]any); ok {
+			segments := make([]string, 0, len(path))
+			for _, seg := range path {
+				segments = append(segments, fmt.Sprint(seg))
+			}
+			apiErr.Field = strings.Join(segments, signDot)
+		}
+		apiErrs = append(apiErrs, apiErr)
+	}
+
+	return apiErrs
+}
+
+// joinAPIErrors joins the messages of the errors into a failure reason.
+func joinAPIErrors(apiErrs []APIError) string {
+	msgs := make([]string, 0, len(apiErrs))
+	for _, e := range apiErrs {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// newStatusError creates a StatusError from the stored response of the client instance.
+func newStatusError(resp *Response) *StatusError {
+	status := resp.R.Status
+	if isEmptyString(status) {
+		status = fmt.Sprintf("%d", resp.Status)
+	}
+
+	return &StatusError{
+		StatusCode: resp.Status,
+		Status:     status,
+		Body:       resp.bs,
+	}
+}
+
+// classifyError returns the kind of an error returned by the transport.
+// Timeouts (of the http client or of the request context) are KindTimeout, a canceled request context
+// is KindCanceled, others are KindNetwork.
+func classifyError(err error) ErrorKind {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return KindCanceled
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return KindTimeout
+	}
+
+	return KindNetwork
+}
+
+// bodySnippet returns the beginning of a raw body, at most size bytes with its whitespace collapsed,
+// to quote it in a failure reason.
+func bodySnippet(body []byte, size int) string {
+	truncated := len(body) > size
+	if truncated {
+		body = body[:size]
+		// do not cut a multi-byte character
+		for len(body) > 0 && !utf8.Valid(body) {
+			body = body[:len(body)-1]
+		}
+	}
+
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if truncated {
+		snippet += "..."
+	}
+
+	return snippet
+}
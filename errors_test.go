@@ -0,0 +1,202 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSend_ErrorKind(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	tests := []struct {
+		name   string
+		client func() *Client[H]
+		want   ErrorKind
+	}{
+		{"network", func() *Client[H] {
+			return New[H]().SetRequest(MethodGet, "http://127.0.0.1:1/ping")
+		}, KindNetwork},
+		{"timeout", func() *Client[H] {
+			return New[H]().Optional(Lambda[H](func(c *Client[H]) {
+				c.Config.Timeout = 50 * time.Millisecond
+			})).SetRequest(MethodGet, slow.URL)
+		}, KindTimeout},
+		{"response header timeout", func() *Client[H] {
+			return New[H]().Optional(WithResponseHeaderTimeout[H](50*time.Millisecond)).SetRequest(MethodGet, slow.URL)
+		}, KindTimeout},
+		{"canceled", func() *Client[H] {
+			return New[H]().Optional(WithTransport[H](canceledTransport{})).SetRequest(MethodGet, slow.URL)
+		}, KindCanceled},
+		{"decode", func() *Client[H] {
+			return New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusOK, "<html></html>").URL)
+		}, KindDecode},
+		{"status", func() *Client[H] {
+			return New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusNotFound, `{"code":0}`).URL)
+		}, KindStatus},
+		{"business", func() *Client[H] {
+			return New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusBadRequest, `{"code":1001,"msg":"invalid"}`).URL)
+		}, KindBusiness},
+		{"request", func() *Client[H] {
+			return New[H]().SetRequest(MethodGet, "http://example.org/users/:id")
+		}, KindRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c := tt.client().Send(); c.Exception.Kind != tt.want {
+				t.Errorf("kind = %q, want %q (%+v)", c.Exception.Kind, tt.want, c.Exception)
+			}
+		})
+	}
+}
+
+// canceledTransport sends the requests with a canceled context, like a caller giving up.
+type canceledTransport struct{}
+
+func (canceledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	return http.DefaultTransport.RoundTrip(req.WithContext(ctx))
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{&url.Error{Op: "Get", URL: "http://example.org", Err: context.DeadlineExceeded}, KindTimeout},
+		{&url.Error{Op: "Get", URL: "http://example.org", Err: context.Canceled}, KindCanceled},
+		{&url.Error{Op: "Get", URL: "http://example.org", Err: errors.New("connection refused")}, KindNetwork},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSend_UndecodableStatus(t *testing.T) {
+	srv := newTestServer(t, http.StatusBadGateway, "<html>\n<h1>502 Bad Gateway</h1>\n</html>")
+
+	c := New[H]().SetRequest(MethodGet, srv.URL).Send()
+	if c.Exception.Kind != KindStatus || c.Exception.StatusError == nil {
+		t.Fatalf("exception = %+v, want a status error", c.Exception)
+	}
+	if c.Exception.StatusError.StatusCode != http.StatusBadGateway || c.Exception.PanicError != nil {
+		t.Errorf("exception = %+v", c.Exception)
+	}
+	if want := "unexpected http status: 502 Bad Gateway: <html> <h1>502 Bad Gateway</h1> </html>"; c.Exception.FailureReason != want {
+		t.Errorf("reason = %q, want %q", c.Exception.FailureReason, want)
+	}
+}
+
+func TestBodySnippet(t *testing.T) {
+	if got := bodySnippet([]byte("héllo world"), 2); got != "h..." {
+		t.Errorf("bodySnippet() = %q, want %q", got, "h...")
+	}
+}
+
+func TestTry(t *testing.T) {
+	data, err := New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusOK, `{"code":0,"data":{"id":1}}`).URL).Send().Try()
+	if err != nil || data["id"] != float64(1) {
+		t.Errorf("data = %v, err = %v", data, err)
+	}
+
+	_, err = New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusOK, `{"code":1001,"msg":"invalid"}`).URL).Send().Try()
+	var bizErr *BusinessError
+	if !errors.As(err, &bizErr) || bizErr.Code != 1001 {
+		t.Errorf("err = %v, want a business error", err)
+	}
+
+	_, err = New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusNotFound, `{"code":404,"msg":"no such user"}`).URL).Send().Try()
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want a status error", err)
+	}
+}
+
+func TestUnwrapOr(t *testing.T) {
+	fallback := H{"id": 0}
+
+	c := New[H]().SetRequest(MethodGet, "http://127.0.0.1:1/ping").Send()
+	if c.IsOk() || !c.IsErr() || c.UnwrapOr(fallback)["id"] != 0 {
+		t.Errorf("a failed request should unwrap to the default value")
+	}
+
+	c = New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusOK, `{"code":0,"data":{"id":1}}`).URL).Send()
+	if !c.IsOk() || c.IsErr() || c.UnwrapOr(fallback)["id"] != float64(1) {
+		t.Errorf("a successful request should unwrap to the data")
+	}
+
+	// a business failure of a 2xx response is a failure, like for Try
+	c = New[H]().SetRequest(MethodGet, newTestServer(t, http.StatusOK, `{"code":1001,"data":{"id":1}}`).URL).Send()
+	if _, err := c.Try(); err == nil || c.IsOk() || !c.IsErr() || c.UnwrapOr(fallback)["id"] != 0 {
+		t.Errorf("a business failure should unwrap to the default value, err = %v", err)
+	}
+}
+
+func TestWithErrorsKey(t *testing.T) {
+	body := `{"code":40001,"msg":"invalid users","error":{"details":[` +
+		`{"field":"users.0.email","message":"is required","code":"required"},` +
+		`{"path":["users",1,"age"],"msg":"must be positive","code":42},` +
+		`"rate limited"]}}`
+	srv := newTestServer(t, http.StatusUnprocessableEntity, body)
+
+	c := New[H](WithErrorsKey[H]("error.details")).SetRequest(MethodPost, srv.URL).Send()
+	errs := c.Exception.Errors()
+	if c.Exception.Kind != KindBusiness || len(errs) != 3 {
+		t.Fatalf("exception = %+v, errors = %+v", c.Exception, errs)
+	}
+	if errs[0].Field != "users.0.email" || errs[0].Code != "required" || errs[0].Error() != "users.0.email: is required" {
+		t.Errorf("errors[0] = %+v", errs[0])
+	}
+	if errs[1].Field != "users.1.age" || errs[1].Code != "42" || errs[1].Message != "must be positive" {
+		t.Errorf("errors[1] = %+v", errs[1])
+	}
+	if errs[2].Message != "rate limited" || errs[2].Raw != nil {
+		t.Errorf("errors[2] = %+v", errs[2])
+	}
+
+	srv = newTestServer(t, http.StatusOK, `{"code":0,"errors":[{"message":"partially failed"}]}`)
+	c = New[H](WithErrorsKey[H]("errors")).SetRequest(MethodGet, srv.URL).Send()
+	if c.Exception.Kind != KindBusiness || c.Exception.FailureReason != "partially failed" {
+		t.Errorf("exception = %+v, want the errors of a 200 response", c.Exception)
+	}
+
+	srv = newTestServer(t, http.StatusOK, `{"code":0,"errors":[]}`)
+	if c = New[H](WithErrorsKey[H]("errors")).SetRequest(MethodGet, srv.URL).Send(); !isEmpty(c.Exception) {
+		t.Errorf("exception = %+v, want none for an empty errors array", c.Exception)
+	}
+}
+
+func TestException_Unwrap(t *testing.T) {
+	c := New[H]().Optional(WithTransport[H](canceledTransport{})).SetRequest(MethodGet, "http://127.0.0.1:1/ping").Send()
+
+	_, err := c.Try()
+	var e *Exception
+	if !errors.As(err, &e) || e.Kind != KindCanceled || !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want an Exception wrapping context.Canceled", err)
+	}
+	if err.Error() != c.Exception.PanicError.Error() {
+		t.Errorf("Error() = %q, want %q", err.Error(), c.Exception.PanicError.Error())
+	}
+
+	defer func() {
+		if r, ok := recover().(string); !ok || r != c.Exception.PanicError.Error() {
+			t.Errorf("Unwrap() panicked with %v, want the message of the error", r)
+		}
+	}()
+	c.Unwrap()
+}
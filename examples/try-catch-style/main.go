@@ -6,7 +6,6 @@ package main
 
 import (
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/pokeyaro/gloria"
@@ -86,12 +85,12 @@ func main() {
 			fmt.Printf("Failed reason is %v\n", e.PanicError.Error())
 		}).
 		Finally(func(c *gloria.Client[Result]) {
-			switch statusCode, retCode := c.EchoCode(); {
-			case statusCode == http.StatusOK && retCode == gloria.OkCode:
+			switch _, retCode := c.EchoCode(); {
+			case c.IsSuccessStatus() && retCode == gloria.OkCode:
 				fmt.Println("Business success!")
-			case statusCode == http.StatusOK && retCode != gloria.OkCode:
+			case c.IsSuccessStatus() && retCode != gloria.OkCode:
 				fmt.Println("Business failure!")
-			case statusCode != http.StatusOK:
+			case !c.IsSuccessStatus():
 				fmt.Println("Response failed!")
 			default:
 				panic("Unknown error!")
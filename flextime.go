@@ -0,0 +1,89 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// epochMillisThreshold separates epoch seconds from epoch milliseconds, any value above it
+// (year 5138 in seconds) is considered as milliseconds.
+const epochMillisThreshold = 1e11
+
+var (
+	// timeLayoutsMu guards timeLayouts
+	timeLayoutsMu sync.RWMutex
+
+	// timeLayouts are the layouts tried by FlexTime after the epochs, in registration order
+	timeLayouts = []string{time.RFC3339Nano}
+)
+
+// FlexTime is a time.Time which decodes from epoch seconds, epoch milliseconds (as a number or a
+// numeric string), RFC3339, and the layouts registered with RegisterTimeLayout.
+// It encodes as RFC3339, like time.Time.
+type FlexTime struct {
+	time.Time
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *FlexTime) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+
+	s := string(bytes.Trim(data, `"`))
+	if epoch, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if epoch > epochMillisThreshold || epoch < -epochMillisThreshold {
+			t.Time = time.UnixMilli(epoch)
+		} else {
+			t.Time = time.Unix(epoch, 0)
+		}
+		return nil
+	}
+
+	timeLayoutsMu.RLock()
+	defer timeLayoutsMu.RUnlock()
+
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot parse %q as a time", s)
+}
+
+// RegisterTimeLayout registers a time layout, such as "2006-01-02 15:04:05", which FlexTime fields try
+// after epoch seconds, epoch milliseconds and RFC3339 while decoding, in registration order.
+// Use FlexTime instead of time.Time in T for fields whose format differs from RFC3339.
+// The layouts are global: they apply to the responses of every client of the process, so register
+// them once at startup, such as in an init function.
+//
+// Example usage:
+//
+//	type Item struct {
+//		Ctime gloria.FlexTime `json:"ctime"`
+//	}
+//
+//	func init() {
+//		gloria.RegisterTimeLayout("2006-01-02 15:04:05")
+//	}
+func RegisterTimeLayout(layout string) {
+	timeLayoutsMu.Lock()
+	defer timeLayoutsMu.Unlock()
+
+	for _, l := range timeLayouts {
+		if l == layout {
+			return
+		}
+	}
+	timeLayouts = append(timeLayouts, layout)
+}
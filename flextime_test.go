@@ -0,0 +1,54 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFlexTime_UnmarshalJSON(t *testing.T) {
+	RegisterTimeLayout("2006-01-02 15:04:05")
+	want := time.Date(2023, 7, 1, 8, 30, 0, 0, time.UTC)
+
+	tests := []string{
+		`1688200200`,
+		`"1688200200"`,
+		`1688200200000`,
+		`"2023-07-01T08:30:00Z"`,
+		`"2023-07-01 08:30:00"`,
+	}
+	for _, in := range tests {
+		var ft FlexTime
+		if err := ft.UnmarshalJSON([]byte(in)); err != nil || !ft.Equal(want) {
+			t.Errorf("UnmarshalJSON(%s) = %v, %v, want %v", in, ft.Time, err, want)
+		}
+	}
+
+	var ft FlexTime
+	if err := ft.UnmarshalJSON([]byte(`"yesterday"`)); err == nil {
+		t.Error("UnmarshalJSON(yesterday) error = nil")
+	}
+}
+
+func TestSend_TimeLayout(t *testing.T) {
+	type item struct {
+		Ctime FlexTime `json:"ctime"`
+		Utime FlexTime `json:"utime"`
+	}
+
+	RegisterTimeLayout("02/01/2006")
+	srv := newTestServer(t, http.StatusOK, `{"code":0,"msg":"ok","data":{"ctime":1688200200,"utime":"01/07/2023"}}`)
+	c := New[item]().
+		SetRequest(MethodGet, srv.URL).
+		Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if c.Data().Ctime.Unix() != 1688200200 || c.Data().Utime.Day() != 1 || c.Data().Utime.Month() != time.July {
+		t.Errorf("data = %+v", c.Data())
+	}
+}
@@ -6,9 +6,14 @@ package gloria
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -21,7 +26,8 @@ Constructor to create a client instance
 */
 
 // New function returns an empty template initialization (for rest mode).
-func New[T any]() *Client[T] {
+// The optional opts are applied to the client instance, like the Optional method.
+func New[T any](opts ...ClientFunc[T]) *Client[T] {
 	client := &Client[T]{
 		Context: &Context{
 			HttpClient: &http.Client{},
@@ -35,14 +41,18 @@ func New[T any]() *Client[T] {
 			Logger:        nil,
 			IsRestMode:    true,
 			DefaultOkCode: OkCode,
+			SlowThreshold: TimeoutShort,
 			JSONLoader:    NativeJSONLibrary{},
+			EnvelopeKeys:  defaultEnvelopeKeys,
+			CompressAbove: defaultCompressThreshold,
 		},
 		Exception:     &Exception{},
 		Result:        &RESTFulResp[T]{},
-		beforeRequest: []func(*Client[T]) error{},
-		afterResponse: []func(*Client[T]) error{},
+		beforeRequest: []hook[T]{},
+		afterResponse: []hook[T]{},
 		urls:          &urls{},
 		params:        SMap{},
+		pathParams:    SMap{},
 		authorization: &authorization{},
 		headers: &header{
 			cookies: []*http.Cookie{},
@@ -51,7 +61,7 @@ func New[T any]() *Client[T] {
 		payload: nil,
 	}
 
-	return client
+	return client.Optional(opts...)
 }
 
 // Default function returns a basic default template.
@@ -64,11 +74,13 @@ func New[T any]() *Client[T] {
 //  5. Timeout: 30s
 //  6. RestMode: true
 //
-// Inject default middleware:
+// Inject default middleware, which applies each header unless it is set by the user:
 //  1. set Accept: application/json
 //  2. set Content-Type: application/json
 //  3. set Content-Language: en-US,en;q=0.9
 //  4. set User-Agent: - actual environment
+//
+// The options registered for the host of the request are applied once it is resolved, see RegisterHost.
 func Default[T any]() *Client[T] {
 	// Create an empty client object
 	client := New[T]()
@@ -89,19 +101,20 @@ func Default[T any]() *Client[T] {
 	)
 
 	// Add hook action (load default request middleware)
-	client.UsePreHooks(func(c *Client[T]) error {
-		if isEmpty(c.headers) {
-			c.headers = &header{
-				accept:      JsonContentType,
-				contentType: JsonContentType,
-				language:    LocaleEn,
-				userAgent:   getUserAgent(),
-			}
-		}
+	// Each default header only fills a header which is not set by the user, through a dedicated setter
+	// (such as SetContentType) or through SetHeader, so the custom headers are always preserved.
+	client.UsePreHookNamed(HookDefaultHeaders, func(c *Client[T]) error {
+		c.headers.setDefault(&c.headers.accept, HeaderAcceptKey, JsonContentType)
+		c.headers.setDefault(&c.headers.contentType, HeaderContentTypeKey, JsonContentType)
+		c.headers.setDefault(&c.headers.language, HeaderContentLanguageKey, LocaleEn)
+		c.headers.setDefault(&c.headers.userAgent, HeaderUserAgentKey, getUserAgent())
 
 		return nil
 	})
 
+	// Apply the options registered for the host of the request, once it is resolved
+	client.hostDefaults = newHostDefaults(client.Config)
+
 	return client
 }
 
@@ -176,6 +189,104 @@ func WithTimeout[T any](timeout time.Duration) ClientFunc[T] {
 	}
 }
 
+// WithTotalDeadline is a ClientFunc[T] function that configures the total deadline of a client
+// instance.
+// Unlike WithTimeout which bounds each attempt, the deadline bounds all the attempts of a request
+// together, including the retries and the waits between them, so the retries stop once it passes.
+func WithTotalDeadline[T any](d time.Duration) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.TotalTimeout = d
+	}
+}
+
+// WithRetry is a ClientFunc[T] function that configures the retries of a client instance.
+// It takes the number of retries count and the wait duration between the attempts as parameters.
+// A request is retried on network errors and on the 429 and 5xx statuses. The Retry-After header of a
+// 429 or 503 response (in seconds or as an http date) replaces the wait duration, and the request is
+// not retried if that wait would exceed the total deadline (see WithTotalDeadline).
+// Note: Please set an idempotency key (see SetIdempotencyKey) before retrying POST or PATCH requests.
+func WithRetry[T any](count int, wait time.Duration) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.RetryCount = count
+		c.Config.RetryWait = wait
+	}
+}
+
+// WithStats is a ClientFunc[T] function that registers a Stats collector for a client instance.
+// The duration of every request sent by the client is recorded into the collector, which can be
+// shared by many clients to report aggregate percentiles.
+func WithStats[T any](s *Stats) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.Stats = s
+	}
+}
+
+// WithMetricsHook is a ClientFunc[T] function that registers a hook called exactly once after each Send
+// of a client instance completes, successful or not, even when the request is retried. The hook receives
+// the RequestMetrics of the request, such as its status, its duration and its error kind, so that they
+// can be pushed to a metrics backend without gloria depending on it.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithMetricsHook[T](func(m gloria.RequestMetrics) {
+//		requestDuration.WithLabelValues(m.Method, strconv.Itoa(m.Status)).Observe(m.Duration.Seconds())
+//	}))
+func WithMetricsHook[T any](fn func(m RequestMetrics)) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.MetricsHook = fn
+	}
+}
+
+// WithSlowThreshold is a ClientFunc[T] function that sets the slow request threshold of a client
+// instance.
+// It takes a time.Duration value d as a parameter and returns a ClientFunc[T].
+// When logging is enabled, requests taking longer than the threshold are logged at the WARN level.
+// The default threshold is TimeoutShort.
+func WithSlowThreshold[T any](d time.Duration) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.SlowThreshold = d
+	}
+}
+
+// WithBaseURL is a ClientFunc[T] function that sets the base URL of a client instance.
+// A relative path passed to SetRequest or the shorthand functions (such as GET) is resolved
+// against it, so that the host is configured once for many endpoints.
+// The path is always resolved below the base URL, that is "/users" and "users" both resolve to
+// "https://example.org/api/v1/users" with the base URL "https://example.org/api/v1".
+func WithBaseURL[T any](base string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.BaseURL = base
+	}
+}
+
+// WithRawCommaParams is a ClientFunc[T] function that keeps the commas of the query parameters
+// literal instead of percent-encoding them as "%2C", for the APIs which expect the lists joined with
+// commas (such as a []string param) literally, like "mime_types=png,gif".
+func WithRawCommaParams[T any](raw bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.RawCommaParams = raw
+	}
+}
+
+// WithProxy is a ClientFunc[T] function that sets the proxy of a client instance, such as
+// "http://proxy.internal:3128" or "socks5://127.0.0.1:1080", the credentials of the proxy can be set in
+// the url. An invalid proxy url is recorded as an Exception when the request is created. The proxy does
+// not apply to a custom transport (see WithTransport).
+func WithProxy[T any](proxyURL string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.Proxy = proxyURL
+	}
+}
+
+// WithStrictURL is a ClientFunc[T] function that sets the StrictURL configuration of a client instance.
+// When strict is true, SetSchema and SetHost panic on invalid input as they used to; otherwise the
+// invalid input is recorded as an Exception and the request is not sent.
+func WithStrictURL[T any](strict bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.StrictURL = strict
+	}
+}
+
 // WithSkipTLS is a ClientFunc[T] function that sets the SkipTLS configuration of a client
 // instance.
 // It takes a boolean value skipTLS as a parameter and returns a ClientFunc[T].
@@ -187,6 +298,20 @@ func WithSkipTLS[T any](skipTLS bool) ClientFunc[T] {
 	}
 }
 
+// WithRedactHeaders is a ClientFunc[T] function that extends the list of headers whose values are
+// replaced by "***" wherever headers are printed, such as by Echo and the debug dump of a client instance.
+// The Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key headers are always masked,
+// and the header names are case-insensitive. The headers sent are not affected.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithRedactHeaders[T]("X-Auth-Token", "X-Signature"))
+func WithRedactHeaders[T any](keys ...string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.RedactHeaders = append(append([]string(nil), c.Config.RedactHeaders...), keys...)
+	}
+}
+
 // WithIsDebug is a ClientFunc[T] function that sets the IsDebug configuration of a client instance.
 // It takes a boolean value isDebug.
 func WithIsDebug[T any](isDebug bool) ClientFunc[T] {
@@ -209,10 +334,21 @@ func WithUseLogger[T any](enabled bool) ClientFunc[T] {
 			// logger := log.New(os.Stdout, "", log.Lshortfile|log.Ldate|log.Ltime)
 			logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 			c.Config.Logger = logger
+			// Colors are only enabled when the output is attached to a terminal.
+			c.Config.Color = isTerminal(os.Stdout)
 		}
 	}
 }
 
+// WithColor is a ClientFunc[T] function that enables or disables the ANSI colors of the log output.
+// By default, colors are auto-detected by the WithUseLogger function according to whether the
+// output is a terminal, this function overrides the detection result.
+func WithColor[T any](enabled bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.Color = enabled
+	}
+}
+
 // WithRegisterJsonLibrary is a ClientFunc[T] function that registers the json library for a
 // client instance.
 // You can choose the popular json parsing library independently.
@@ -223,6 +359,421 @@ func WithRegisterJsonLibrary[T any](lib JSONLibrary) ClientFunc[T] {
 	}
 }
 
+// WithAPIVersion is a ClientFunc[T] function that sets the api version segment of the request paths,
+// such as "v1", for the APIs serving several versions, the version is switched per call by SetAPIVersion.
+// The segment replaces the ":version" placeholder of the path if any, such as in SetBaseURI("/api/:version"),
+// which configures its position, otherwise it is inserted at the start of the path.
+//
+// Example usage:
+//
+//	c := gloria.New[User]().Optional(
+//		gloria.WithBaseURL[User]("https://api.example.org/api/:version"),
+//		gloria.WithAPIVersion[User]("v1"),
+//	)
+//	c.SetRequest(gloria.MethodGet, "/users/7").Send() // GET https://api.example.org/api/v1/users/7
+func WithAPIVersion[T any](version string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.APIVersion = version
+	}
+}
+
+// WithBodyDecryptor is a ClientFunc[T] function that registers the decryption of the response body, for
+// the APIs returning encrypted bodies with a key exchanged out of band. The decryptor receives the raw
+// body once it is read by Send, and the decrypted body feeds the usual decoding (see UseBodyTransform,
+// which it is registered with, so it should be applied once). A failure is recorded as a KindDecode
+// Exception. It may be passed to Configure, but not to RegisterHost, which panics on body transforms.
+//
+// Example usage:
+//
+//	// the body is the nonce followed by the AES-GCM sealed json document
+//	block, _ := aes.NewCipher(key)
+//	gcm, _ := cipher.NewGCM(block)
+//	c := gloria.New[User]().Optional(gloria.WithBodyDecryptor[User](func(b []byte) ([]byte, error) {
+//		if len(b) < gcm.NonceSize() {
+//			return nil, errors.New("body too short")
+//		}
+//		return gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+//	}))
+func WithBodyDecryptor[T any](fn func([]byte) ([]byte, error)) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.UseBodyTransform(func(b []byte) ([]byte, error) {
+			plain, err := fn(b)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt: %w", err)
+			}
+			return plain, nil
+		})
+	}
+}
+
+// WithIndentedJSON is a ClientFunc[T] function that sets the indentation of the marshaled payload,
+// like json.MarshalIndent, whatever the registered json library, for the debugging (such as the output
+// of ToCurl) and for the servers expecting readable bodies. The payload is compact by default.
+//
+// Example usage:
+//
+//	c := gloria.New[User]().Optional(gloria.WithIndentedJSON[User]("", "  "))
+func WithIndentedJSON[T any](prefix, indent string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.JSONPrefix = prefix
+		c.Config.JSONIndent = indent
+	}
+}
+
+// WithAllowGetBody is a ClientFunc[T] function that sets whether the request shorthands (such as Request)
+// send the payload of a GET request, which is omitted by default, for the search APIs which require a
+// body on GET, such as Elasticsearch. A client instance always sends the payload set by SetPayload.
+//
+// Example usage:
+//
+//	gloria.Configure(gloria.WithAllowGetBody[any](true))
+//	resp := gloria.Request[gloria.H]("http://localhost:9200/logs/_search", nil, query)(gloria.MethodGet)
+func WithAllowGetBody[T any](enabled bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.AllowGetBody = enabled
+	}
+}
+
+// WithMaxRedirects is a ClientFunc[T] function that sets the maximum number of redirects followed by a
+// request, 10 by default. A negative number disables the redirects, the redirect response is returned
+// as is. A request redirected to a url it already visited is aborted at once, as a loop, instead of
+// exhausting the limit. Both failures are recorded as an Exception wrapping ErrRedirectLoop or
+// ErrTooManyRedirects, and they are not retried.
+//
+// Example usage:
+//
+//	c := gloria.New[User]().Optional(gloria.WithMaxRedirects[User](3))
+func WithMaxRedirects[T any](n int) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.MaxRedirects = n
+	}
+}
+
+// WithClientTrace is a ClientFunc[T] function that sets whether the phase durations of the requests
+// are traced with httptrace, for a latency breakdown beyond Meta.Duration. The dns lookup, the tcp
+// connection, the tls handshake and the time to first byte of the last attempt are recorded into
+// Meta.DNSTime, Meta.ConnectTime, Meta.TLSTime and Meta.TTFB, and summarized by EchoTrace.
+// The phases of a reused connection are zero.
+//
+// Example usage:
+//
+//	c := gloria.New[User]().Optional(gloria.WithClientTrace[User](true))
+//	c.SetRequest(gloria.MethodGet, "https://example.com/users/7").Send()
+//	fmt.Println(c.EchoTrace())
+func WithClientTrace[T any](enabled bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.ClientTrace = enabled
+	}
+}
+
+// WithUseNumber is a ClientFunc[T] function that sets whether the numbers of the interface{} values
+// of the response, such as the values of an H or an any field, are decoded into json.Number instead
+// of float64, which silently loses the precision of the integers above 2^53, such as 64-bit IDs.
+// The typed fields are decoded as usual. The registered json library is used if it implements
+// NumberJSONLibrary, otherwise the numbers are decoded by encoding/json.
+//
+// Example usage:
+//
+//	c := gloria.New[gloria.H]().Optional(gloria.WithUseNumber[gloria.H](true))
+//	id, _ := c.Data()["id"].(json.Number).Int64()
+func WithUseNumber[T any](enabled bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.UseNumber = enabled
+	}
+}
+
+// WithAcceptStatus is a ClientFunc[T] function that sets the http status codes which are accepted
+// as a successful response.
+// By default any 2xx status is accepted; when codes are provided, only those codes are accepted and
+// any other status is recorded as an Exception.
+func WithAcceptStatus[T any](codes ...int) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.AcceptStatuses = codes
+	}
+}
+
+// WithOkCodes is a ClientFunc[T] function that sets the business success codes of a client
+// instance in rest mode.
+// A response is successful if its business code is any of the codes, for APIs answering with
+// several success codes such as 0 and 200. The first code becomes the DefaultOkCode.
+// It is a no-op if no code is provided.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithOkCodes[T](0, 200))
+func WithOkCodes[T any](codes ...int) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.DefineOkCode(codes...)
+	}
+}
+
+// WithSuccessFunc is a ClientFunc[T] function that sets the business success determination of a
+// client instance in rest mode.
+// The fn function inspects the raw response body and reports whether the business call succeeded,
+// it replaces the comparison of the code with DefaultOkCode. This supports APIs returning
+// non-numeric codes such as "OK" or "E1001", whose code is then not decoded into Result.Code.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(
+//		gloria.WithSuccessFunc[T](func(raw []byte) bool {
+//			return bytes.Contains(raw, []byte(`"code":"OK"`))
+//		}),
+//	)
+func WithSuccessFunc[T any](fn func(raw []byte) bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.SuccessFunc = fn
+	}
+}
+
+// WithDataPath is a ClientFunc[T] function that sets the dot-path of the data subtree of a client
+// instance, such as "data.result.items". A numeric segment indexes an array, like "data.items.0".
+// The Send method extracts the subtree at that path of the whole response body before decoding it
+// into T, which avoids defining wrapper structs for deeply nested payloads.
+//
+// Example usage:
+//
+//	c := gloria.New[[]Item]().Optional(gloria.WithDataPath[[]Item]("data.result.items"))
+func WithDataPath[T any](jsonPath string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.DataPath = jsonPath
+	}
+}
+
+// WithErrorsKey is a ClientFunc[T] function that sets the dot-path of the errors array of the response
+// body of a client instance, such as "errors" or "error.details". The Send method decodes the entries
+// of that array into the Errors of the Exception, and a non-empty array in a successful response is
+// recorded as a KindBusiness Exception. This represents the multiple validation errors of batch APIs,
+// which a single FailureReason cannot.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithErrorsKey[T]("errors"))
+//	for _, e := range c.Exception.Errors() {
+//		fmt.Println(e.Field, e.Message)
+//	}
+func WithErrorsKey[T any](key string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.ErrorsKey = key
+	}
+}
+
+// WithKeyTransform is a ClientFunc[T] function that sets how the keys of the json response body are
+// rewritten before decoding, such as KeySnakeToCamel. This allows decoding the responses of an API
+// whose naming convention differs from the json tags of T. It is a no-op (KeyCaseNone) by default.
+//
+// Example usage:
+//
+//	c := gloria.New[User]().Optional(gloria.WithKeyTransform[User](gloria.KeySnakeToCamel))
+func WithKeyTransform[T any](mode KeyCase) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.KeyCase = mode
+	}
+}
+
+// WithRequestCompression is a ClientFunc[T] function that compresses the marshaled request payload of a
+// client instance with the given content encoding, CompressionGzip or CompressionDeflate, and sets the
+// Content-Encoding header accordingly.
+// Payloads smaller than 1 KB are sent as is to avoid the overhead, see WithCompressionThreshold.
+// The body of SetBodyReader is never compressed.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithRequestCompression[T](gloria.CompressionGzip))
+func WithRequestCompression[T any](algo string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.Compression = algo
+	}
+}
+
+// WithCompressionThreshold is a ClientFunc[T] function that sets the minimum size in bytes of a request
+// payload compressed by WithRequestCompression, 1 KB by default.
+func WithCompressionThreshold[T any](size int) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.CompressAbove = size
+	}
+}
+
+// WithRequestID is a ClientFunc[T] function that generates a unique request id (an UUID) for each request
+// of a client instance, sent in the headerName header, X-Request-ID if empty. An id already set (such as
+// with SetHeader or SendWith) is kept. The id sent is returned by RequestID, and it is included in the
+// request log, which correlates the client and the server logs.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithRequestID[T](""))
+func WithRequestID[T any](headerName string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		if isEmptyString(headerName) {
+			headerName = HeaderRequestIDKey
+		}
+		c.Config.RequestIDHeader = headerName
+	}
+}
+
+// WithCertPinning is a ClientFunc[T] function that pins the server certificates of a client instance.
+// The sha256Fingerprints parameter lists the SHA-256 fingerprints of the accepted leaf certificates, as
+// hex strings with or without colons (such as the output of `openssl x509 -fingerprint -sha256`).
+// A connection to a server whose certificate is not in the list is rejected, and the request records
+// an Exception whose PanicError wraps ErrCertNotPinned.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithCertPinning[T]([]string{
+//		"9F:86:D0:81:88:4C:7D:65:9A:2F:EA:A0:C5:5A:D0:15:A3:BF:4F:1B:2B:0B:82:2C:D1:5D:6C:15:B0:F0:0A:08",
+//	}))
+func WithCertPinning[T any](sha256Fingerprints []string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.CertPins = sha256Fingerprints
+	}
+}
+
+// WithForceHTTP2 is a ClientFunc[T] function that forces HTTP/2 for the requests of a client instance.
+// Without it HTTP/2 is negotiated over TLS, with a fallback to HTTP/1.1. With it HTTP/1.1 is disabled,
+// and when h2c is true, http:// URLs use cleartext HTTP/2 (h2c) with prior knowledge, such as for gRPC
+// gateways or service meshes. Cleartext HTTP/2 requires Go 1.24, otherwise the request records an Exception.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithForceHTTP2[T](true))
+func WithForceHTTP2[T any](h2c bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.HTTP2 = true
+		c.Config.H2C = h2c
+	}
+}
+
+// WithDialTimeout is a ClientFunc[T] function that sets the maximum amount of time of establishing a
+// connection of a client instance, including the dns resolution. It allows failing fast on a slow dns
+// or an unreachable host, regardless of the overall Timeout. Zero means no limit.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithDialTimeout[T](2 * time.Second))
+func WithDialTimeout[T any](timeout time.Duration) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.DialTimeout = timeout
+	}
+}
+
+// WithTLSHandshakeTimeout is a ClientFunc[T] function that sets the maximum amount of time of the tls
+// handshake of a client instance. Zero means no limit.
+func WithTLSHandshakeTimeout[T any](timeout time.Duration) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.TLSHandshakeTimeout = timeout
+	}
+}
+
+// WithResponseHeaderTimeout is a ClientFunc[T] function that sets the maximum amount of time to wait for
+// the response headers of a client instance, once the request is written. It bounds a half-open or
+// stalled connection without limiting the download of a large body. Zero means no limit.
+func WithResponseHeaderTimeout[T any](timeout time.Duration) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.ResponseHeaderTimeout = timeout
+	}
+}
+
+// WithDefaultHeaders is a ClientFunc[T] function that adds standing headers to every request of a client
+// instance, such as an API key or a tenant id, set at construction time. Repeated calls merge the headers.
+// They have the lowest precedence: a header of the same name set by SetHeader, SetHeaders, a dedicated
+// setter (such as SetContentType) or SendWith overrides them for the request.
+//
+// Example usage:
+//
+//	c := gloria.New[T](gloria.WithDefaultHeaders[T](gloria.H{"X-Api-Key": key}))
+func WithDefaultHeaders[T any](h H) ClientFunc[T] {
+	return func(c *Client[T]) {
+		merged := make(SMap, len(c.Config.DefaultHeaders)+len(h))
+		for k, v := range c.Config.DefaultHeaders {
+			merged[k] = v
+		}
+		for k, v := range convertToSMap(h) {
+			merged[http.CanonicalHeaderKey(k)] = v
+		}
+		c.Config.DefaultHeaders = merged
+	}
+}
+
+// WithTransport is a ClientFunc[T] function that sets a custom transport of a client instance, which
+// replaces the default transport, such as a MockTransport in tests, or an instrumented transport.
+// The transport settings of the client (such as SkipTLS, the connection timeouts and the HTTP/2
+// selection) do not apply to a custom transport, while the logger still wraps it.
+//
+// Example usage:
+//
+//	mock := gloria.NewMockTransport()
+//	mock.On(gloria.MethodGet, "/users/:id").Respond(http.StatusOK, gloria.H{"code": 0})
+//	c := gloria.New[T]().Optional(gloria.WithTransport[T](mock))
+func WithTransport[T any](rt http.RoundTripper) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.Transport = rt
+	}
+}
+
+// WithCassette is a ClientFunc[T] function that plugs a VCR-style cassette into a client instance.
+// In ModeRecord each request is sent and the request and response pair is saved to the file at path,
+// which is overwritten by the recording of the client instance (send all the requests to record through
+// the same client instance), in ModeReplay the recorded responses are served without network, and an unrecorded request fails.
+// A request is matched by its method, its url and the hash of its body. This stabilizes the tests
+// against flaky third-party APIs.
+//
+// Example usage:
+//
+//	mode := gloria.ModeReplay
+//	if os.Getenv("RECORD") != "" {
+//		mode = gloria.ModeRecord
+//	}
+//	c := gloria.New[T]().Optional(gloria.WithCassette[T]("testdata/users.json", mode))
+func WithCassette[T any](path string, mode RecordMode) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.Cassette = NewCassette(path, mode)
+	}
+}
+
+// WithOmitEmpty is a ClientFunc[T] function that omits the empty fields of the marshaled payload of a
+// client instance, like the omitempty option of encoding/json but without tagging the struct fields:
+// null, false, 0, "", {} and [] fields are omitted, at any depth.
+// It applies to the output of any json library registered with WithRegisterJsonLibrary.
+//
+// Example usage:
+//
+//	c := gloria.New[T]().Optional(gloria.WithOmitEmpty[T](true))
+func WithOmitEmpty[T any](omit bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.OmitEmpty = omit
+	}
+}
+
+// WithOmitNull is a ClientFunc[T] function that sets how the nil pointers, maps and slices of the payload
+// of a client instance are rendered: omitted if omit is true, or as an explicit null by default.
+// This suits the APIs which reject an explicit null, while accepting the zero values.
+func WithOmitNull[T any](omit bool) ClientFunc[T] {
+	return func(c *Client[T]) {
+		c.Config.OmitNull = omit
+	}
+}
+
+// WithEnvelopeKeys is a ClientFunc[T] function that sets the json keys of the rest mode envelope
+// of a client instance.
+// By default the envelope is {"code": ..., "msg": ..., "data": ...}, this function allows
+// decoding other shapes, such as {"errcode": ..., "errmsg": ..., "result": ...}.
+// An empty key keeps the default one.
+func WithEnvelopeKeys[T any](codeKey, msgKey, dataKey string) ClientFunc[T] {
+	return func(c *Client[T]) {
+		keys := defaultEnvelopeKeys
+		if !isEmpty(codeKey) {
+			keys.Code = codeKey
+		}
+		if !isEmpty(msgKey) {
+			keys.Msg = msgKey
+		}
+		if !isEmpty(dataKey) {
+			keys.Data = dataKey
+		}
+		c.Config.EnvelopeKeys = keys
+	}
+}
+
 // Deprecated: WithFilterSlash is a ClientFunc[T] function that sets the FilterSlash configuration of a client instance.
 // It takes a boolean parameter filterSlash to enable or disable filtering of trailing slashes in URLs.
 // When filterSlash is set to true, the client will remove any trailing slashes from the URLs it sends requests to.
@@ -248,6 +799,7 @@ func WithDisabledRestMode[T any]() ClientFunc[T] {
 func WithModifySuccessCode[T any](code int) ClientFunc[T] {
 	return func(c *Client[T]) {
 		c.Config.DefaultOkCode = code
+		c.Config.OkCodes = nil
 	}
 }
 
@@ -268,8 +820,20 @@ func (c *Client[T]) FilterUrlSlash() *Client[T] {
 	return c
 }
 
-func (c *Client[T]) DefineOkCode(code int) *Client[T] {
-	c.Config.DefaultOkCode = code
+// DefineOkCode sets the business success codes of the client in rest mode, replacing the previous ones.
+// A response is successful if its business code is any of the codes, the first one becomes the
+// DefaultOkCode. It is a no-op if no code is provided.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	c.DefineOkCode(0, http.StatusOK)
+func (c *Client[T]) DefineOkCode(codes ...int) *Client[T] {
+	if len(codes) == 0 {
+		return c
+	}
+	c.Config.DefaultOkCode = codes[0]
+	c.Config.OkCodes = append([]int(nil), codes...)
 
 	return c
 }
@@ -295,14 +859,19 @@ func (c *Client[T]) Query(q string) string {
 	return qs[q]
 }
 
-// QueryParams returns the query parameters as a SMap from the client instance.
+// QueryParams returns a copy of the query parameters as a SMap from the client instance, so that
+// mutating it does not affect the client, see RemoveQueryParam to edit them.
 func (c *Client[T]) QueryParams() SMap {
 	qs := c.params
 	if isEmpty(qs) {
 		return nil
 	}
 
-	return qs
+	cp := make(SMap, len(qs))
+	for k, v := range qs {
+		cp[k] = v
+	}
+	return cp
 }
 
 // Header returns the value of the specified header key from the client's request context.
@@ -322,6 +891,12 @@ func (c *Client[T]) Headers() http.Header {
 	return headers
 }
 
+// IdempotencyKey returns the "Idempotency-Key" header value of the client instance, the one of the
+// last request once sent.
+func (c *Client[T]) IdempotencyKey() string {
+	return c.idempotency
+}
+
 // Cookie returns the cookie with the specified name from the client's request context.
 // If the cookie is found, it returns the cookie object.
 // If the cookie is not found, it returns an error.
@@ -344,7 +919,8 @@ func (c *Client[T]) Cookies() []*http.Cookie {
 */
 
 // SetMethod sets the HTTP method for the client instance to the specified method.
-// This method must be one of the supported methods: GET, POST, PUT, PATCH, DELETE, HEAD, or OPTIONS.
+// This method must be one of the supported methods: GET, POST, PUT, PATCH, DELETE, HEAD, or OPTIONS,
+// or a custom method registered with RegisterMethod.
 //
 // If an unsupported method is provided, this method will panic with an error message indicating the supported methods.
 //
@@ -386,6 +962,9 @@ func (c *Client[T]) SetURL(scheme, host, baseUri, endpoint string) *Client[T] {
 
 // SetSchema sets the protocol scheme for the client instance.
 //
+// An unsupported scheme is recorded as an Exception which short-circuits Send, or panics
+// when the StrictURL configuration is enabled.
+//
 // This method is called by the SetURL method to set the complete URL for the client instance.
 //
 // See SetURL.
@@ -396,7 +975,16 @@ func (c *Client[T]) SetSchema(scheme string) *Client[T] {
 
 	if scheme != ProtocolHttp && scheme != ProtocolHttps {
 		c.ChalkStr(LogLevelPanic, "scheme parameter: only support http/https protocol header.")
-		panic("scheme parameter: only support http/https protocol header")
+		if c.Config.StrictURL {
+			panic("scheme parameter: only support http/https protocol header")
+		}
+		c.failBuild(&Exception{
+			CodeLocation:   fileLocation(2),
+			Kind:           KindRequest,
+			PanicError:     fmt.Errorf("scheme parameter: only support http/https protocol header, got %q", scheme),
+			OccurrenceTime: time.Now().Unix(),
+		})
+		return c
 	}
 
 	c.urls.scheme = scheme
@@ -406,6 +994,9 @@ func (c *Client[T]) SetSchema(scheme string) *Client[T] {
 
 // SetHost sets the host URL for the client instance.
 //
+// An invalid host is recorded as an Exception which short-circuits Send, or panics
+// when the StrictURL configuration is enabled.
+//
 // This method is called by the SetURL method to set the complete URL for the client instance.
 //
 // See SetURL.
@@ -416,10 +1007,25 @@ func (c *Client[T]) SetHost(host string) *Client[T] {
 
 	if !isValidHost(host) && !isValidIPAddrPort(host) {
 		c.ChalkStr(LogLevelPanic, "Invalid host or IP address with port.")
-		panic("Invalid host or IP address with port.")
+		if c.Config.StrictURL {
+			panic("Invalid host or IP address with port.")
+		}
+		c.failBuild(&Exception{
+			CodeLocation:   fileLocation(2),
+			Kind:           KindRequest,
+			PanicError:     fmt.Errorf("invalid host or IP address with port: %q", host),
+			OccurrenceTime: time.Now().Unix(),
+		})
+		return c
+	}
+
+	// A bare IPv6 address must be bracketed in the URL
+	if isBareIPv6(host) {
+		host = fmt.Sprintf("[%s]", host)
 	}
 
 	c.urls.host = strings.TrimRight(host, signSlash)
+	applyHostOptions(c, c.urls.host)
 
 	return c
 }
@@ -434,11 +1040,8 @@ func (c *Client[T]) SetBaseURI(baseUri string) *Client[T] {
 		c.ChalkStr(LogLevelDebug, "BaseUri parameter is not set, it is recommended to follow the principle of minimal URLs when setting it.")
 	}
 
-	signCollect := [2]string{signSlash, signHorizontal}
-
-	for _, v := range signCollect {
-		c.urls.baseURI = strings.TrimRight(baseUri, v)
-	}
+	// Trim the trailing slashes and horizontal lines together, such as "/api/-" to "/api"
+	c.urls.baseURI = strings.TrimRight(baseUri, signSlash+signHorizontal)
 
 	return c
 }
@@ -477,40 +1080,36 @@ func (c *Client[T]) SetEndpoint(endpoint string) *Client[T] {
 //
 // Dynamic Routing:
 // The SetRequest function supports dynamic routing by allowing you to replace path
-// parameters in the request path with actual values. If there is only one path
-// parameter, you can use the placeholder ":id" in the path. If there are two path
-// parameters, the first one should be replaced with ":id" and the second one with ":sid".
+// parameters in the request path with actual values. Each path segment starting with
+// ":" (such as ":id", ":sid" or ":org") is a placeholder, and the placeholders are
+// replaced positionally by the url-escaped values of pathParams, from left to right.
 //
 // Example:
 //
 //	client := NewClient()
 //	client.SetRequest("GET", "/users/:id", "123")
 //	client.SetRequest("POST", "/users/:id/:sid", "123", "456")
+//	client.SetRequest("GET", "/orgs/:org/teams/:team/members/:user", "acme", "core", "mystic")
 //
 // In the above example, the first SetRequest call sets the request method to "GET" and
 // the request path to "/users/123". The second SetRequest call sets the request method
-// to "POST" and the request path to "/users/123/456".
+// to "POST" and the request path to "/users/123/456". The third SetRequest call sets
+// the request path to "/orgs/acme/teams/core/members/mystic".
 //
 // Note:
-// The SetRequest function currently supports a maximum of two dynamic routing parameters.
-// If more than two path parameters are provided, a panic will occur.
+// Values exceeding the number of placeholders are ignored. The placeholders without
+// a corresponding value may be filled by SetPathParam, a placeholder still unfilled when
+// the request is sent records a KindRequest Exception.
+// A relative path (without scheme and host) is resolved against the base URL set by
+// the WithBaseURL function.
+// The query parameters of the path are added to the existing query parameters, see
+// SetQueryParams for the precedence.
 func (c *Client[T]) SetRequest(method, path string, pathParams ...string) *Client[T] {
 	// Parse Dynamic Routing
-	var tempPath string
-	switch len(pathParams) {
-	case 0:
-		tempPath = path
-	case 1:
-		tempPath = strings.Replace(path, ":id", pathParams[0], 1)
-	case 2:
-		tempPath = strings.Replace(path, ":id", pathParams[0], 1)
-		tempPath = strings.Replace(tempPath, ":sid", pathParams[1], 1)
-	case 3:
-		panic("There are too many dynamic routing parameters, which are not supported for now!")
-	}
+	tempPath := replacePathParams(path, pathParams)
 
-	// Parse the URL
-	parseUrl := urlSegments(tempPath)
+	// Parse the URL, a relative path is resolved against the base URL
+	parseUrl := urlSegments(c.resolveURL(tempPath))
 
 	// Set the request method
 	c.SetMethod(method)
@@ -526,8 +1125,68 @@ func (c *Client[T]) SetRequest(method, path string, pathParams ...string) *Clien
 	return c
 }
 
+// SetAPIVersion sets the api version segment of the request path, such as "v2", which overrides the
+// version set by WithAPIVersion for this client instance, so that the version is switched per call.
+// An empty version restores the one of WithAPIVersion.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	c := gloria.New[User]().Optional(gloria.WithAPIVersion[User]("v1"))
+//	c.SetRequest(gloria.MethodGet, "https://api.example.org/users/7").SetAPIVersion("v2").Send()
+//	// GET https://api.example.org/v2/users/7
+func (c *Client[T]) SetAPIVersion(version string) *Client[T] {
+	c.apiVersion = strings.Trim(version, signSlash)
+
+	return c
+}
+
+// version returns the api version segment of the request path, set by SetAPIVersion or WithAPIVersion.
+func (c *Client[T]) version() string {
+	if !isEmptyString(c.apiVersion) {
+		return c.apiVersion
+	}
+	return strings.Trim(c.Config.APIVersion, signSlash)
+}
+
+// SetPathParam sets a named path parameter for the request.
+// It takes a key and value as parameters, the ":key" placeholder of the request path will be
+// replaced by the url-escaped value when the request is sent.
+// It returns a pointer to the Client instance, allowing for method chaining.
+//
+// Example usage:
+//
+//	client.SetRequest("GET", "/users/:user").SetPathParam("user", "mystic")
+func (c *Client[T]) SetPathParam(key, value string) *Client[T] {
+	c.pathParams[key] = value
+
+	return c
+}
+
+// SetPathParams sets multiple named path parameters for the request.
+// Each ":key" placeholder of the request path is replaced by the url-escaped value of the same key
+// when the request is sent, so it does not depend on the order of the placeholders.
+// It can be called before or after SetRequest, and a placeholder left unfilled is recorded as an
+// Exception instead of sending a broken URL.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.SetRequest("GET", "/orgs/:org/teams/:team").SetPathParams(SMap{
+//		"org":  "acme",
+//		"team": "core",
+//	})
+func (c *Client[T]) SetPathParams(params SMap) *Client[T] {
+	for key, value := range params {
+		c.pathParams[key] = value
+	}
+
+	return c
+}
+
 // SetQueryParam sets a query parameter for the request.
 // It takes a key and value as parameters and adds them to the params map of the Client instance.
+// An existing parameter with the same key is overridden, see SetQueryParams for the precedence.
 // It returns a pointer to the Client instance, allowing for method chaining.
 //
 // Example usage:
@@ -539,30 +1198,106 @@ func (c *Client[T]) SetQueryParam(key, value string) *Client[T] {
 	return c
 }
 
-// SetQueryParams sets multiple query parameters for the request.
-// It takes a `params` map as a parameter and converts it to the `SMap` type, which is used to
-// store query parameters in the `Client` instance.
-// The `params` map contains key-value pairs where the keys represent the query parameter names
-// and the values represent the query parameter values.
-// This method replaces any existing query parameters in the `params` map of the `Client`
-// instance with the new ones.
+// SetQueryParams sets multiple query parameters for the request.
+// It takes a `params` map as a parameter and converts it to the `SMap` type, which is used to
+// store query parameters in the `Client` instance.
+// The `params` map contains key-value pairs where the keys represent the query parameter names
+// and the values represent the query parameter values.
+// This method is additive: the new parameters are merged into the existing query parameters of the
+// `Client` instance, and only the parameters with the same key are replaced.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Precedence:
+// SetQueryParam, SetQueryParams and the query string parsed by SetRequest all merge into the same
+// parameters, so for a given key the last call wins, regardless of which method sets it.
+//
+// Example usage:
+//
+//	params := H{"key1": "value1", "key2": "value2"}
+//	client.SetQueryParams(params)
+func (c *Client[T]) SetQueryParams(params H) *Client[T] {
+	tempParams := convertToSMap(params)
+
+	if c.params == nil {
+		c.params = make(SMap, len(tempParams))
+	}
+
+	for key, value := range tempParams {
+		c.params[key] = value
+	}
+	return c
+}
+
+// RemoveQueryParam removes a query parameter set by SetQueryParam, SetQueryParams or the url of
+// SetRequest, which is handy to edit a reused client instance.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.RemoveQueryParam("page")
+func (c *Client[T]) RemoveQueryParam(key string) *Client[T] {
+	delete(c.params, key)
+
+	return c
+}
+
+// ClearQueryParams removes all the query parameters, including the raw query of SetRawQuery.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.ClearQueryParams().SetQueryParam("page", "1")
+func (c *Client[T]) ClearQueryParams() *Client[T] {
+	c.params = SMap{}
+	c.rawQuery = ""
+
+	return c
+}
+
+// SetQueryStruct sets the query parameters for the request from the fields of a struct.
+// It takes a `v` parameter, which is a struct or a pointer to a struct, whose fields are named by their
+// `query:"name"` tag, or by their field name if untagged. A field tagged `query:"-"` is skipped, a nil
+// pointer is omitted, and a zero value is omitted with the omitempty option, such as `query:"page,omitempty"`.
+// The values are converted like SetQueryParams, slices being joined with commas, and the fields of an
+// embedded struct are promoted. The parameters are merged like SetQueryParams.
+// A value which is not a struct is recorded as an Exception.
 // It returns a pointer to the `Client` instance to allow for method chaining.
 //
 // Example usage:
 //
-//	params := H{"key1": "value1", "key2": "value2"}
-//	client.SetQueryParams(params)
-func (c *Client[T]) SetQueryParams(params H) *Client[T] {
-	tempParams := convertToSMap(params)
-
-	if isEmpty(c.params) {
-		c.params = tempParams
+//	type Filter struct {
+//		Status string   `query:"status,omitempty"`
+//		Page   int      `query:"page"`
+//		Tags   []string `query:"tags,omitempty"`
+//	}
+//	client.SetQueryStruct(Filter{Status: "open", Page: 2})
+func (c *Client[T]) SetQueryStruct(v any) *Client[T] {
+	params, err := structToQuery(v)
+	if err != nil {
+		c.failBuild(&Exception{
+			CodeLocation:   fileLocation(1),
+			Kind:           KindRequest,
+			PanicError:     err,
+			OccurrenceTime: time.Now().Unix(),
+		})
 		return c
 	}
 
-	for key, value := range tempParams {
-		c.params[key] = value
-	}
+	return c.SetQueryParams(params)
+}
+
+// SetRawQuery sets a pre-encoded query string for the request.
+// The raw query is appended verbatim to the url, after the query parameters set by SetQueryParam
+// or SetQueryParams, bypassing their encoding. This is useful for signed urls whose signature
+// covers a specific encoding, which would be broken by re-encoding.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.SetRawQuery("expires=1700000000&signature=abc%2Bdef%3D")
+func (c *Client[T]) SetRawQuery(raw string) *Client[T] {
+	c.rawQuery = strings.TrimPrefix(raw, "?")
+
 	return c
 }
 
@@ -570,13 +1305,18 @@ func (c *Client[T]) SetQueryParams(params H) *Client[T] {
 // It takes a `key` and `value` as parameters and adds the header to the `Client` instance.
 // The `key` parameter represents the header key, and the `value` parameter represents the header value.
 // This method allows adding custom headers to the request.
+// The key is canonicalized like http.Header.Set, so that "x-trace" and "X-Trace" are the same header,
+// the last value set wins.
 // It returns a pointer to the `Client` instance to allow for method chaining.
 //
 // Example usage:
 //
 //	client.SetHeader("Content-Type", "application/json")
 func (c *Client[T]) SetHeader(key, value string) *Client[T] {
-	c.headers.extra[key] = value
+	if c.headers.extra == nil {
+		c.headers.extra = SMap{}
+	}
+	c.headers.extra[http.CanonicalHeaderKey(key)] = value
 
 	return c
 }
@@ -584,7 +1324,7 @@ func (c *Client[T]) SetHeader(key, value string) *Client[T] {
 // SetHeaders sets multiple custom headers for the request.
 // It takes a `headers` parameter, which is a map[string]string representing the headers to be set.
 // Each key-value pair in the `headers` map corresponds to a header key and value, respectively.
-// This method allows setting multiple custom headers at once.
+// This method allows setting multiple custom headers at once, the keys are canonicalized like SetHeader.
 // It returns a pointer to the `Client` instance to allow for method chaining.
 //
 // Example usage:
@@ -597,14 +1337,62 @@ func (c *Client[T]) SetHeader(key, value string) *Client[T] {
 func (c *Client[T]) SetHeaders(headers H) *Client[T] {
 	parseHeaders := convertToSMap(headers)
 
-	if isEmpty(c.headers.extra) {
-		c.headers.extra = parseHeaders
-		return c
+	if c.headers.extra == nil {
+		c.headers.extra = make(SMap, len(parseHeaders))
 	}
-
 	for key, value := range parseHeaders {
-		c.headers.extra[key] = value
+		c.headers.extra[http.CanonicalHeaderKey(key)] = value
+	}
+	return c
+}
+
+// SetIfNoneMatch makes the request conditional with the If-None-Match header, so that the server
+// responds 304 Not Modified if the resource still has the entity tag `etag` (see ETag), instead of
+// sending it again. Such a response is not an Exception, check NotModified to keep the cached data.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	c := client.SetIfNoneMatch(cached.ETag).Send()
+//	if c.NotModified() {
+//		return cached.Data
+//	}
+func (c *Client[T]) SetIfNoneMatch(etag string) *Client[T] {
+	return c.SetHeader(HeaderIfNoneMatchKey, etag)
+}
+
+// RemoveHeader removes a header set by SetHeader or SetHeaders, or by a dedicated setter such as
+// SetContentType, SetUserAgent or the authorization setters. The name is case-insensitive.
+// Note that the standing headers of WithDefaultHeaders are kept, and that the default headers of
+// Default (such as Accept) are filled again when the request is created.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.RemoveHeader("X-Trace")
+func (c *Client[T]) RemoveHeader(key string) *Client[T] {
+	key = http.CanonicalHeaderKey(key)
+	for k := range c.headers.extra {
+		if http.CanonicalHeaderKey(k) == key {
+			delete(c.headers.extra, k)
+		}
 	}
+
+	switch key {
+	case HeaderAcceptKey:
+		c.headers.accept = ""
+	case HeaderContentTypeKey:
+		c.headers.contentType = ""
+	case HeaderContentLanguageKey:
+		c.headers.language = ""
+	case HeaderUserAgentKey:
+		c.headers.userAgent = ""
+	case HeaderAuthorizationKey:
+		c.authorization = &authorization{}
+	case "Cookie":
+		c.headers.cookies = nil
+	}
+
 	return c
 }
 
@@ -693,6 +1481,34 @@ func (c *Client[T]) SetBearerAuth(token string) *Client[T] {
 	return c
 }
 
+// SetIdempotencyKey sets the value of the "Idempotency-Key" header for the request.
+// It takes a `key` parameter, which is a string used by the server to deduplicate the request.
+// The key is stored on the client instance and stamped on every attempt of the request, so that
+// a retried POST or PATCH is not applied twice.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.SetIdempotencyKey("order-20231016-0001")
+func (c *Client[T]) SetIdempotencyKey(key string) *Client[T] {
+	c.idempotency = key
+	c.autoIdemKey = false
+
+	return c
+}
+
+// SetAutoIdempotencyKey sets a randomly generated UUID as the "Idempotency-Key" header for the request.
+// A new key is generated for each Send of the client instance, and kept across the retries of that
+// Send, so that another request of a reused client is not discarded as a duplicate.
+// See SetIdempotencyKey.
+func (c *Client[T]) SetAutoIdempotencyKey() *Client[T] {
+	c.idempotency = newUUID()
+	c.autoIdemKey = true
+	c.idemKeySent = false
+
+	return c
+}
+
 // SetAccept sets the value of the "Accept" header for the request.
 // It takes an `accept` parameter, which is a string representing the value of the "Accept" header.
 // This method allows specifying the desired media type for the response.
@@ -749,6 +1565,31 @@ func (c *Client[T]) SetUserAgent(ua string) *Client[T] {
 	return c
 }
 
+// AppendUserAgent appends a product token to the "User-Agent" header for the request.
+// It takes a `fragment` parameter, which is a product token identifying the calling application,
+// such as "MyService/1.2". Unlike SetUserAgent, the gloria and Go identification is kept, or the
+// User-Agent previously set by SetUserAgent.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.AppendUserAgent("MyService/1.2")
+//	// User-Agent: Gloria/1.0.0 (linux amd64) Go/1.20.5 MyService/1.2
+func (c *Client[T]) AppendUserAgent(fragment string) *Client[T] {
+	fragment = strings.TrimSpace(fragment)
+	if isEmptyString(fragment) {
+		return c
+	}
+
+	// Start from the gloria identification, unless a User-Agent is already set
+	if isEmptyString(c.headers.userAgent) {
+		c.headers.userAgent = getUserAgent()
+	}
+	c.headers.userAgent += " " + fragment
+
+	return c
+}
+
 // SetJsonPayload sets the JSON payload for the request.
 // It takes a `data` parameter, which is a map[string]any representing the JSON data to be sent in the request body.
 // This method is used for making JSON-encoded POST or PUT requests.
@@ -763,6 +1604,7 @@ func (c *Client[T]) SetUserAgent(ua string) *Client[T] {
 //	client.SetJsonPayload(payload)
 func (c *Client[T]) SetJsonPayload(data H) *Client[T] {
 	c.payload = data
+	c.hasPayload = data != nil
 
 	return c
 }
@@ -770,6 +1612,8 @@ func (c *Client[T]) SetJsonPayload(data H) *Client[T] {
 // SetPayload sets the payload for the request.
 // It takes a `data` parameter of any type representing the data to be sent in the request body.
 // This method is used for making generic POST or PUT requests.
+// A payload which is set is sent even if it is a zero value (such as a struct with zero fields), only
+// a nil payload sends no body.
 // It returns a pointer to the `Client` instance to allow for method chaining.
 //
 // Example usage:
@@ -778,10 +1622,151 @@ func (c *Client[T]) SetJsonPayload(data H) *Client[T] {
 //	client.SetPayload(payload)
 func (c *Client[T]) SetPayload(data any) *Client[T] {
 	c.payload = data
+	c.hasPayload = !isNil(data)
+
+	return c
+}
+
+// SetEmptyJSONBody sets an empty JSON object `{}` as the payload for the request, with the
+// application/json Content-Type, for the strict endpoints which reject a request without a body.
+// It is a shorthand of SetPayload(gloria.H{}) which also sets the Content-Type.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.SetRequest(gloria.MethodPost, "https://example.com/jobs/7/cancel").SetEmptyJSONBody()
+func (c *Client[T]) SetEmptyJSONBody() *Client[T] {
+	c.SetPayload(H{})
+	c.headers.contentType = JsonContentType
+
+	return c
+}
+
+// SetFormStruct sets the request body from the fields of a struct, encoded as a form with the
+// application/x-www-form-urlencoded Content-Type, for the APIs which only accept form bodies.
+// It takes a `v` parameter, which is a struct or a pointer to a struct, whose fields are named by their
+// `form:"name"` tag, or by their field name if untagged. The fields are handled like SetQueryStruct:
+// a field tagged `form:"-"` is skipped, a nil pointer is omitted, a zero value is omitted with the
+// omitempty option, and the fields of an embedded struct are promoted. The values of a slice are
+// repeated under the same key, such as "tags=a&tags=b".
+// The form is sent like SetBodyReader, so it takes precedence over SetPayload.
+// A value which is not a struct is recorded as an Exception.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	type Login struct {
+//		Username string   `form:"username"`
+//		Password string   `form:"password"`
+//		Scopes   []string `form:"scope,omitempty"`
+//	}
+//	client.SetRequest(gloria.MethodPost, "/oauth/token").SetFormStruct(Login{Username: "john", Password: "secret"})
+func (c *Client[T]) SetFormStruct(v any) *Client[T] {
+	form, err := structToForm(v)
+	if err != nil {
+		c.failBuild(&Exception{
+			CodeLocation:   fileLocation(1),
+			Kind:           KindRequest,
+			PanicError:     err,
+			OccurrenceTime: time.Now().Unix(),
+		})
+		return c
+	}
+
+	encoded := form.Encode()
+	return c.SetBodyReader(strings.NewReader(encoded), FormContentType, int64(len(encoded)))
+}
+
+// ExpectContentType sets the Content-Type expected for the response, as an opt-in guard before decoding.
+// It takes a `ct` parameter, such as "application/json", matched as a prefix of the Content-Type of the
+// response, ignoring its parameters (such as the charset) and the case.
+// A successful response of another Content-Type, such as the html login page of a proxy with a 200
+// status, is recorded as a KindDecode Exception wrapping ErrUnexpectedContentType, with a snippet of the
+// body, instead of a confusing decode error. An empty body is not checked.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.ExpectContentType(gloria.JsonContentType)
+func (c *Client[T]) ExpectContentType(ct string) *Client[T] {
+	c.expectType = ct
+
+	return c
+}
+
+// SetBodyReader sets a reader as the request body, which is streamed as is instead of being marshaled.
+// It takes an `r` parameter, which is the reader of the body, a `contentType` parameter, which sets the
+// Content-Type header unless empty, and a `length` parameter, which sets the Content-Length header,
+// use -1 if the length is unknown to send a chunked body.
+// This method avoids buffering large uploads in memory, and takes precedence over SetPayload.
+// The reader is consumed by the Send method, so a streamed body is not retried. A reader which is an
+// io.Seeker (such as a file) is rewound to its start for another Send of the client instance, another
+// reader must be set again, otherwise the request is recorded as a KindRequest Exception wrapping
+// ErrBodyConsumed.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	f, _ := os.Open("backup.tar.gz")
+//	defer f.Close()
+//	info, _ := f.Stat()
+//	client.SetBodyReader(f, "application/gzip", info.Size())
+func (c *Client[T]) SetBodyReader(r io.Reader, contentType string, length int64) *Client[T] {
+	c.bodyReader = r
+	c.bodyLength = length
+	c.bodyRead = false
+	if !isEmptyString(contentType) {
+		c.headers.contentType = contentType
+	}
+
+	return c
+}
+
+// SetUploadProgress sets a callback reporting the progress of the request body upload, such as for the
+// progress bar of a CLI tool.
+// It takes a `fn` parameter, which is called with the bytes sent so far and the total length as the
+// transport reads the body, the total is -1 if it is unknown (such as a chunked SetBodyReader body).
+// The progress restarts from zero when the request is retried.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.SetBodyReader(f, "application/gzip", info.Size()).SetUploadProgress(func(sent, total int64) {
+//		fmt.Printf("\r%d/%d bytes", sent, total)
+//	})
+func (c *Client[T]) SetUploadProgress(fn func(sent, total int64)) *Client[T] {
+	c.progress = fn
+
+	return c
+}
+
+// SetContext sets the context of the request, which cancels the request and its retries once it is
+// done, such as on the shutdown of a server. The total deadline (see WithTotalDeadline) is derived from it.
+// A canceled request is recorded as a KindCanceled Exception, and a request past the deadline of the
+// context as a KindTimeout one. The context of a single call can be set by WithRequestContext instead.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.SetContext(r.Context()).SetRequest(gloria.MethodGet, "/users").Send()
+func (c *Client[T]) SetContext(ctx context.Context) *Client[T] {
+	c.ctx = ctx
 
 	return c
 }
 
+// requestContext returns the context of the request, the one of the ongoing SendWith call takes
+// precedence over the one of SetContext, it defaults to context.Background.
+func (c *Client[T]) requestContext() context.Context {
+	if c.sendOpts != nil && c.sendOpts.ctx != nil {
+		return c.sendOpts.ctx
+	}
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
 /*
 	Internal chain methods with Setter attribute for the Client struct
 */
@@ -817,6 +1802,38 @@ func (c *Client[T]) sonic() *Client[T] {
 	Internal Methods and Functions for Construct Client Struct
 */
 
+// resolveURL resolves a relative path against the base URL of the client instance.
+// The path is returned as is if it is absolute (with scheme or host) or if no base URL is set.
+//
+// This internal function is called by the SetRequest method and the shorthand functions.
+func (c *Client[T]) resolveURL(path string) string {
+	if isEmptyString(c.Config.BaseURL) {
+		return path
+	}
+
+	if ref, err := url.Parse(path); err != nil || ref.IsAbs() || !isEmpty(ref.Host) {
+		return path
+	}
+	ref, err := url.Parse(strings.TrimLeft(path, signSlash))
+	if err != nil {
+		return path
+	}
+
+	base, err := url.Parse(c.Config.BaseURL)
+	if err != nil {
+		panic(fmt.Errorf("base URL parsing error: %w", err))
+	}
+	// Make sure the last segment of the base path is kept when resolving
+	if !strings.HasSuffix(base.Path, signSlash) {
+		base.Path += signSlash
+		if !isEmpty(base.RawPath) {
+			base.RawPath += signSlash
+		}
+	}
+
+	return base.ResolveReference(ref).String()
+}
+
 // createRequest creates and prepares an HTTP request based on the client instance's configuration.
 // It sets the request method, URL, body, headers, authentication, cookies, and other request configurations.
 // The created request is stored in the client's context.
@@ -831,16 +1848,61 @@ func (c *Client[T]) createRequest() *Client[T] {
 	}
 
 	// Parsing the full url path and query params
-	c.parseFullURLPath()
+	if err := c.parseFullURLPath(); err != nil {
+		c.Exception = &Exception{
+			CodeLocation:   fileLocation(1),
+			Kind:           KindRequest,
+			PanicError:     err,
+			OccurrenceTime: time.Now().Unix(),
+		}
+		return c
+	}
 
 	// Parsing the request body
 	var req *http.Request
 	var err error
+	var contentEncoding string
+	ctx := c.requestContext()
+
+	// Check the requested protocols are supported by the toolchain
+	if c.Config.H2C && !h2cSupported {
+		c.Exception = &Exception{
+			CodeLocation:   fileLocation(1),
+			Kind:           KindRequest,
+			PanicError:     errors.New("h2c requires go1.24 or later"),
+			OccurrenceTime: time.Now().Unix(),
+		}
+		return c
+	}
 
 	// Set request body
-	if isEmpty(c.payload) {
-		// such as GET
-		req, err = http.NewRequest(c.Meta.Method, c.Meta.Url, nil)
+	if c.bodyReader != nil {
+		// such as a large upload, streamed without marshaling, a reader consumed by a previous request is rewound
+		if c.bodyRead {
+			seeker, ok := c.bodyReader.(io.Seeker)
+			if !ok {
+				err = ErrBodyConsumed
+			} else if _, err = seeker.Seek(0, io.SeekStart); err != nil {
+				err = fmt.Errorf("rewind the body reader: %w", err)
+			}
+			if err != nil {
+				c.Exception = &Exception{
+					CodeLocation:   fileLocation(1),
+					Kind:           KindRequest,
+					PanicError:     err,
+					OccurrenceTime: time.Now().Unix(),
+				}
+				return c
+			}
+		}
+		c.bodyRead = true
+		req, err = http.NewRequestWithContext(ctx, c.Meta.Method, c.Meta.Url, c.bodyReader)
+		if err == nil {
+			req.ContentLength = c.bodyLength
+		}
+	} else if !c.hasPayload {
+		// such as GET, a payload which is set is sent even if it is a zero value (such as an empty struct)
+		req, err = http.NewRequestWithContext(ctx, c.Meta.Method, c.Meta.Url, nil)
 	} else {
 		// such as POST/PUT...
 		var byteData []byte
@@ -848,13 +1910,55 @@ func (c *Client[T]) createRequest() *Client[T] {
 		if err != nil {
 			c.Exception = &Exception{
 				CodeLocation:   fileLocation(1),
+				Kind:           KindRequest,
 				PanicError:     err,
 				OccurrenceTime: time.Now().Unix(),
 			}
 			return c
 		}
+		// Omit the empty or null fields, whatever the json library and the struct tags
+		if c.Config.OmitEmpty || c.Config.OmitNull {
+			byteData, err = pruneJSON(byteData, c.Config.OmitEmpty, c.Config.OmitNull)
+			if err != nil {
+				c.Exception = &Exception{
+					CodeLocation:   fileLocation(1),
+					Kind:           KindRequest,
+					PanicError:     err,
+					OccurrenceTime: time.Now().Unix(),
+				}
+				return c
+			}
+		}
+		// Indent the marshaled body, whatever the json library
+		if !isEmptyString(c.Config.JSONPrefix) || !isEmptyString(c.Config.JSONIndent) {
+			var indented bytes.Buffer
+			if err = json.Indent(&indented, byteData, c.Config.JSONPrefix, c.Config.JSONIndent); err != nil {
+				c.Exception = &Exception{
+					CodeLocation:   fileLocation(1),
+					Kind:           KindRequest,
+					PanicError:     err,
+					OccurrenceTime: time.Now().Unix(),
+				}
+				return c
+			}
+			byteData = indented.Bytes()
+		}
+		// Compress the marshaled body, unless it is too small to be worth it
+		if !isEmptyString(c.Config.Compression) && len(byteData) >= c.Config.CompressAbove {
+			byteData, err = compressBody(c.Config.Compression, byteData)
+			if err != nil {
+				c.Exception = &Exception{
+					CodeLocation:   fileLocation(1),
+					Kind:           KindRequest,
+					PanicError:     err,
+					OccurrenceTime: time.Now().Unix(),
+				}
+				return c
+			}
+			contentEncoding = c.Config.Compression
+		}
 		bodyReader := bytes.NewReader(byteData)
-		req, err = http.NewRequest(c.Meta.Method, c.Meta.Url, bodyReader)
+		req, err = http.NewRequestWithContext(ctx, c.Meta.Method, c.Meta.Url, bodyReader)
 	}
 
 	// Store the request object to the context
@@ -862,19 +1966,38 @@ func (c *Client[T]) createRequest() *Client[T] {
 	if err != nil {
 		c.Exception = &Exception{
 			CodeLocation:   fileLocation(1),
+			Kind:           KindRequest,
 			PanicError:     err,
 			OccurrenceTime: time.Now().Unix(),
 		}
 		return c
 	}
 
-	// Set custom request headers
-	if len(c.headers.extra) > 0 {
-		extraHeaders := make(http.Header, len(c.headers.extra))
-		for k, v := range c.headers.extra {
-			extraHeaders[k] = []string{v}
+	// Report the progress of the upload as the transport reads the body, the retries included
+	if c.progress != nil && req.Body != nil && req.Body != http.NoBody {
+		req.Body = newProgressReader(req.Body, req.ContentLength, c.progress)
+		if getBody := req.GetBody; getBody != nil {
+			req.GetBody = func() (io.ReadCloser, error) {
+				body, errBody := getBody()
+				if errBody != nil {
+					return nil, errBody
+				}
+				return newProgressReader(body, req.ContentLength, c.progress), nil
+			}
 		}
-		req.Header = extraHeaders
+	}
+
+	// Set custom request headers, from the lowest to the highest precedence:
+	//   1. the standing headers of WithDefaultHeaders
+	//   2. the headers of SetHeader and SetHeaders
+	//   3. the dedicated setters, such as SetContentType and SetUserAgent
+	//   4. the transient headers of SendWith
+	// They are merged into the headers of the request, rather than replacing the ones it already holds.
+	for k, v := range c.Config.DefaultHeaders {
+		req.Header.Set(k, v)
+	}
+	for k, v := range c.headers.extra {
+		req.Header.Set(k, v)
 	}
 
 	// Set User-Agent request headers
@@ -892,11 +2015,27 @@ func (c *Client[T]) createRequest() *Client[T] {
 		req.Header.Set(HeaderContentTypeKey, c.headers.contentType)
 	}
 
+	// Set Content-Encoding request headers
+	if !isEmpty(contentEncoding) {
+		req.Header.Set(HeaderContentEncodingKey, contentEncoding)
+	}
+
 	// Set Content-Language request headers
 	if !isEmpty(c.headers.language) {
 		req.Header.Set(HeaderContentLanguageKey, c.headers.language)
 	}
 
+	// Set Idempotency-Key request headers, a generated key is renewed for each request
+	if c.autoIdemKey {
+		if c.idemKeySent {
+			c.idempotency = newUUID()
+		}
+		c.idemKeySent = true
+	}
+	if !isEmpty(c.idempotency) {
+		req.Header.Set(HeaderIdempotencyKey, c.idempotency)
+	}
+
 	// Set Authorization request headers
 	switch c.authorization.authType {
 	case AuthTypeBasic:
@@ -914,8 +2053,48 @@ func (c *Client[T]) createRequest() *Client[T] {
 		}
 	}
 
+	// Set the transient request headers of SendWith, they override the headers above
+	if c.sendOpts != nil {
+		for k, v := range c.sendOpts.headers {
+			req.Header.Set(k, v)
+		}
+	}
+
+	// Set the request id, unless one is already set (such as by SendWith)
+	c.requestID = ""
+	if !isEmptyString(c.Config.RequestIDHeader) {
+		c.requestID = req.Header.Get(c.Config.RequestIDHeader)
+		if isEmptyString(c.requestID) {
+			c.requestID = newUUID()
+			req.Header.Set(c.Config.RequestIDHeader, c.requestID)
+		}
+	}
+
+	return c.createHTTPClient()
+}
+
+// createHTTPClient creates the http client of the request from the Config of the client instance.
+// The created client is stored in the client's context.
+func (c *Client[T]) createHTTPClient() *Client[T] {
+	// Check the proxy url, so that a typo fails here instead of bypassing the proxy
+	if !isEmpty(c.Config.Proxy) {
+		if u, errProxy := url.Parse(c.Config.Proxy); errProxy != nil || isEmpty(u.Scheme) || isEmpty(u.Host) {
+			c.Exception = &Exception{
+				CodeLocation:   fileLocation(1),
+				Kind:           KindRequest,
+				PanicError:     fmt.Errorf("invalid proxy url %q", c.Config.Proxy),
+				OccurrenceTime: time.Now().Unix(),
+			}
+			return c
+		}
+	}
+
 	// Set client request configs
-	client := httpClientDefaultConf(c.Config.Timeout, c.Config.SkipTLS, c.Config.Logger)
+	client := httpClientDefaultConf(c.Config)
+
+	// Record the redirect chain of the request
+	c.Meta.Redirects = nil
+	client.CheckRedirect = c.recordRedirect
 
 	// Store the client object to the context
 	c.Context.HttpClient = client
@@ -927,26 +2106,34 @@ func (c *Client[T]) createRequest() *Client[T] {
 // including scheme, host, base URI, endpoint and query parameters.
 //
 // This internal function is called by the createRequest method to set the complete URL and query param section for the client instance.
+// It returns an error if a path parameter is not filled or if the assembled URL is malformed.
 //
 // See createRequest.
-func (c *Client[T]) parseFullURLPath() {
+func (c *Client[T]) parseFullURLPath() error {
 	var urlPath string
 
-	// Set the url path part
-	if isEmptyString(c.Meta.Url) {
-		u := c.urls
-		if u.baseURI == RootURL {
-			urlPath = fmt.Sprintf("%s://%s%s%s", u.scheme, u.host, "", u.endpoint)
-		} else {
-			urlPath = fmt.Sprintf("%s://%s%s%s", u.scheme, u.host, u.baseURI, u.endpoint)
-		}
+	// Set the url path part, it is rebuilt on every call so that a repeated Send is not stale
+	u := c.urls
+	if u.baseURI == RootURL {
+		urlPath = fmt.Sprintf("%s://%s%s%s", u.scheme, u.host, "", u.endpoint)
+	} else {
+		urlPath = fmt.Sprintf("%s://%s%s%s", u.scheme, u.host, u.baseURI, u.endpoint)
+	}
+
+	// Inject the api version segment, see WithAPIVersion
+	if version := c.version(); !isEmptyString(version) {
+		urlPath = insertAPIVersion(urlPath, u.scheme+"://"+u.host, version)
+	}
+
+	// Fill the named path parameters, any unfilled placeholder is an error
+	urlPath, err := fillPathParams(urlPath, c.pathParams)
+	if err != nil {
+		return err
 	}
 
 	// Set request parameters section
-	switch len(c.params) {
-	case 0:
-		c.Meta.Url = urlPath
-	default:
+	var queries []string
+	if len(c.params) > 0 {
 		// Use url.Values to store query parameters
 		queryParams := url.Values{}
 		for k, v := range c.params {
@@ -954,53 +2141,164 @@ func (c *Client[T]) parseFullURLPath() {
 		}
 
 		// Encode query parameters as URL strings
-		encodedQueryParams := queryParams.Encode()
+		encoded := queryParams.Encode()
+		if c.Config.RawCommaParams {
+			// The comma is a valid query character, some APIs expect the lists literally
+			encoded = strings.ReplaceAll(encoded, "%2C", ",")
+		}
+		queries = append(queries, encoded)
+	}
+	if !isEmpty(c.rawQuery) {
+		// The raw query is pre-encoded, append it verbatim
+		queries = append(queries, c.rawQuery)
+	}
 
+	switch len(queries) {
+	case 0:
+		c.Meta.Url = urlPath
+	default:
 		// Generate the full request path
-		fullURL := fmt.Sprintf("%s?%s", urlPath, encodedQueryParams)
+		fullURL := fmt.Sprintf("%s?%s", urlPath, strings.Join(queries, "&"))
 
 		c.Meta.Url = fullURL
 	}
+
+	// Validate the assembled url, so that a malformed url fails here with a clear message
+	// instead of a cryptic error from http.NewRequest
+	parsedURL, err := url.Parse(c.Meta.Url)
+	if err != nil {
+		return fmt.Errorf("invalid request url %q: %w", c.Meta.Url, err)
+	}
+	if isEmpty(parsedURL.Scheme) || isEmpty(parsedURL.Host) {
+		return fmt.Errorf("invalid request url %q: missing scheme or host", c.Meta.Url)
+	}
+
+	return nil
 }
 
-// httpClientDefaultConf creates and returns a default HTTP client with the specified configurations.
-// The timeout parameter specifies the maximum amount of time to wait for a response.
-// The skipTLS parameter indicates whether to skip TLS certificate verification.
-// The logFmt parameter is an optional logger to log HTTP requests and responses.
-func httpClientDefaultConf(timeout time.Duration, skipTLS bool, logFmt *log.Logger) *http.Client {
-	// Create a new transport object with the following configurations:
-	tr := &http.Transport{
-		// TLSClientConfig is set to skip certificate verification.
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: skipTLS,
-		},
-		// MaxIdleConns specifies the maximum number of idle (keep-alive) connections across all hosts.
-		MaxIdleConns: 10,
-		// MaxIdleConnsPerHost specifies the maximum number of idle (keep-alive) connections per host.
-		MaxIdleConnsPerHost: 10,
-		// IdleConnTimeout specifies the maximum amount of time a connection may remain idle (keep-alive)
-		// before it is closed and removed from the pool.
-		IdleConnTimeout: 60 * time.Second,
+// tlsClientConfig creates the tls configuration of the connections from SkipTLS and CertPins.
+func tlsClientConfig(cfg *Config) *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: cfg.SkipTLS,
+		// VerifyPeerCertificate rejects the servers whose certificate is not pinned, if any.
+		VerifyPeerCertificate: verifyCertPins(cfg.CertPins),
 	}
+}
 
+// httpClientDefaultConf creates and returns a default HTTP client with the specified configurations.
+// The cfg parameter is the configuration of the client instance, the following fields are used:
+//   - Timeout specifies the maximum amount of time to wait for a response.
+//   - DialTimeout, TLSHandshakeTimeout and ResponseHeaderTimeout bound the phases of a connection, zero means no limit.
+//   - SkipTLS indicates whether to skip TLS certificate verification.
+//   - Proxy is the url of the proxy the requests are sent through, if not empty.
+//   - CertPins are the SHA-256 fingerprints of the accepted server certificates, if not empty.
+//   - HTTP2 and H2C force HTTP/2, over TLS and over cleartext respectively.
+//   - Transport replaces the default transport, so the settings above do not apply to it.
+//     The default transport is reused across the requests, see defaultTransport.
+//   - Cassette records the requests sent through the transport, or replays them without network.
+//   - Logger is an optional logger to log HTTP requests and responses.
+//   - Color indicates whether the logger output is colorized.
+//   - SlowThreshold is the request duration above which the log level is escalated to WARN.
+func httpClientDefaultConf(cfg *Config) *http.Client {
 	// Create an HTTP client with a timeout for receiving a response.
+	// The custom transport is authoritative, with or without a logger.
 	client := &http.Client{
 		// The maximum amount of time to wait for a response is specified by the Timeout field.
-		Timeout: timeout,
-		// Use the origin default transport object.
-		Transport: http.DefaultTransport,
+		Timeout: cfg.Timeout,
+	}
+
+	// A custom transport replaces the default one, the settings of the default one are then up to it.
+	var base http.RoundTripper
+	if cfg.Transport != nil {
+		base = cfg.Transport
+	} else {
+		base = defaultTransport(cfg)
+	}
+	if cfg.Cassette != nil {
+		base = cfg.Cassette.transport(base)
 	}
 
-	if isEmpty(logFmt) {
+	if isEmpty(cfg.Logger) {
 		// Set the transport object to be used for the HTTP client.
-		client.Transport = tr
+		client.Transport = base
 	} else {
 		// Create a custom Logger transport object.
 		client.Transport = &loggedTransport{
-			transport: tr,
-			logger:    logFmt,
+			transport:     base,
+			logger:        cfg.Logger,
+			color:         cfg.Color,
+			slowThreshold: cfg.SlowThreshold,
+			requestID:     cfg.RequestIDHeader,
 		}
 	}
 
 	return client
 }
+
+// transportKey holds the settings of the default transport, which is rebuilt when one of them changes.
+type transportKey struct {
+	skipTLS               bool
+	certPins              string
+	dialTimeout           time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+	proxy                 string
+	http2                 bool
+	h2c                   bool
+}
+
+// defaultTransport returns the default transport of the configuration, which is built once and then
+// reused by the requests, so that the keep-alive connections are pooled across the calls of Send.
+// It is rebuilt, closing the idle connections of the previous one, when the settings it depends on
+// change (SkipTLS, CertPins, the dial, tls handshake and response header timeouts, Proxy, HTTP2 and H2C).
+func defaultTransport(cfg *Config) *http.Transport {
+	key := transportKey{
+		skipTLS:               cfg.SkipTLS,
+		certPins:              strings.Join(cfg.CertPins, ","),
+		dialTimeout:           cfg.DialTimeout,
+		tlsHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		responseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		proxy:                 cfg.Proxy,
+		http2:                 cfg.HTTP2,
+		h2c:                   cfg.H2C,
+	}
+	if cfg.transport != nil && cfg.transportKey == key {
+		return cfg.transport
+	}
+	if cfg.transport != nil {
+		cfg.transport.CloseIdleConnections()
+	}
+
+	// Create a new transport object with the following configurations:
+	tr := &http.Transport{
+		// TLSClientConfig is set to skip certificate verification, and to check the pinned certificates.
+		TLSClientConfig: tlsClientConfig(cfg),
+		// MaxIdleConns specifies the maximum number of idle (keep-alive) connections across all hosts.
+		MaxIdleConns: 10,
+		// MaxIdleConnsPerHost specifies the maximum number of idle (keep-alive) connections per host.
+		MaxIdleConnsPerHost: 10,
+		// IdleConnTimeout specifies the maximum amount of time a connection may remain idle (keep-alive)
+		// before it is closed and removed from the pool.
+		IdleConnTimeout: 60 * time.Second,
+		// DialContext bounds the dns resolution and the connection establishment.
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		// TLSHandshakeTimeout bounds the tls handshake.
+		TLSHandshakeTimeout: cfg.TLSHandshakeTimeout,
+		// ResponseHeaderTimeout bounds the wait for the response headers, excluding the body download.
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+	}
+
+	// Proxy sends the requests through the proxy, if any, the url is validated by createRequest.
+	if proxyURL, err := url.Parse(cfg.Proxy); err == nil && !isEmpty(cfg.Proxy) {
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	// Select the protocols (HTTP/1.1, HTTP/2 or h2c) deliberately.
+	configureProtocols(tr, cfg)
+
+	cfg.transport, cfg.transportKey = tr, key
+	return tr
+}
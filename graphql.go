@@ -0,0 +1,66 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// graphQLResp is the envelope of a GraphQL response.
+type graphQLResp struct {
+	Data   json.RawMessage `json:"data"`
+	Errors json.RawMessage `json:"errors"`
+}
+
+// SetGraphQL sets a GraphQL query as the payload for the request.
+// It takes a `query` parameter, which is the GraphQL document, and a `variables` parameter, which are
+// the variables of the query, omitted if empty. The payload is the standard `{"query", "variables"}`
+// JSON body, sent with the application/json Content-Type, so the request method is usually POST.
+// In rest mode, the `data` of the response is decoded into the data of the Result, and the messages
+// of the `errors` array, if any, are recorded as the FailureReason of a KindBusiness Exception, and
+// as its Errors.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	client.SetRequest(gloria.MethodPost, "https://api.example.com/graphql").
+//		SetGraphQL(`query ($id: ID!) { user(id: $id) { name } }`, gloria.H{"id": 7})
+func (c *Client[T]) SetGraphQL(query string, variables H) *Client[T] {
+	payload := H{"query": query}
+	if len(variables) > 0 {
+		payload["variables"] = variables
+	}
+
+	c.SetPayload(payload)
+	c.headers.contentType = JsonContentType
+	c.graphql = true
+
+	return c
+}
+
+// decodeGraphQL decodes a GraphQL response body into the Result of the client instance, the business
+// code is the default success code without errors, and FailCode with the joined error messages otherwise.
+func (c *Client[T]) decodeGraphQL(bs []byte) error {
+	var resp graphQLResp
+	if err := c.Config.JSONLoader.Unmarshal(bs, &resp); err != nil {
+		return err
+	}
+
+	if len(resp.Data) > 0 && !bytes.Equal(resp.Data, []byte("null")) {
+		if err := c.jsonLib().Unmarshal(resp.Data, &c.Result.Data); err != nil {
+			return err
+		}
+	}
+
+	c.Result.Code = c.Config.DefaultOkCode
+	c.Result.Msg = ""
+	if apiErrs := parseAPIErrors(c.jsonLib(), resp.Errors); len(apiErrs) > 0 {
+		c.Result.Code = FailCode
+		c.Result.Msg = joinAPIErrors(apiErrs)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetGraphQL(t *testing.T) {
+	var got H
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get(HeaderContentTypeKey)
+		got = nil
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set(HeaderContentTypeKey, JsonContentType)
+		if got["variables"] == nil {
+			_, _ = w.Write([]byte(`{"data":null,"errors":[{"message":"missing id"},{"message":"not allowed"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"user":{"name":"gloria"}}}`))
+	}))
+	defer srv.Close()
+
+	type user struct {
+		User struct {
+			Name string `json:"name"`
+		} `json:"user"`
+	}
+
+	query := `query ($id: ID!) { user(id: $id) { name } }`
+	c := New[user]().SetRequest(MethodPost, srv.URL).SetGraphQL(query, H{"id": 7}).Send()
+	if !isEmpty(c.Exception) || c.Data().User.Name != "gloria" {
+		t.Fatalf("data = %+v, exception = %+v", c.Data(), c.Exception)
+	}
+	if got["query"] != query || got["variables"].(map[string]any)["id"] != float64(7) || contentType != JsonContentType {
+		t.Errorf("body = %v, Content-Type = %q, want the graphql payload", got, contentType)
+	}
+
+	c = New[user]().SetRequest(MethodPost, srv.URL).SetGraphQL(query, nil).Send()
+	if c.Exception.Kind != KindBusiness || c.Exception.FailureReason != "missing id; not allowed" ||
+		len(c.Exception.Errors()) != 2 {
+		t.Errorf("exception = %+v, want the graphql errors", c.Exception)
+	}
+	if _, err := c.Try(); err == nil {
+		t.Error("Try() error = nil, want the graphql errors")
+	}
+}
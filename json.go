@@ -1,7 +1,11 @@
 package gloria
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
 
 	gojson "github.com/goccy/go-json"
 )
@@ -12,6 +16,13 @@ type JSONLibrary interface {
 	Unmarshal(data []byte, v interface{}) error
 }
 
+// NumberJSONLibrary is implemented by the json libraries which can decode the numbers of the
+// interface{} values into json.Number, see WithUseNumber. A library which does not implement it
+// falls back to encoding/json when the numbers are preserved.
+type NumberJSONLibrary interface {
+	UnmarshalUseNumber(data []byte, v interface{}) error
+}
+
 // NativeJSONLibrary is the native implementation of encoding/json.
 type NativeJSONLibrary struct{}
 
@@ -23,6 +34,18 @@ func (l NativeJSONLibrary) Unmarshal(data []byte, v interface{}) error {
 	return json.Unmarshal(data, v)
 }
 
+func (l NativeJSONLibrary) UnmarshalUseNumber(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
 // GoJSONLibrary is an implementation of the popular tripartite library go-json.
 type GoJSONLibrary struct{}
 
@@ -34,24 +57,165 @@ func (l GoJSONLibrary) Unmarshal(data []byte, v interface{}) error {
 	return gojson.Unmarshal(data, v)
 }
 
-// json-iterator implementation
-//type JSONIteratorLibrary struct{}
-//
-//func (l JSONIteratorLibrary) Marshal(v interface{}) ([]byte, error) {
-//	return jsoniter.Marshal(v)
-//}
-//
-//func (l JSONIteratorLibrary) Unmarshal(data []byte, v interface{}) error {
-//	return jsoniter.Unmarshal(data, v)
-//}
+func (l GoJSONLibrary) UnmarshalUseNumber(data []byte, v interface{}) error {
+	dec := gojson.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
 
-// bytedance/sonic implementation
-//type SonicLibrary struct{}
-//
-//func (l SonicLibrary) Marshal(v interface{}) ([]byte, error) {
-//	return sonic.Marshal(v)
-//}
+// numberLibrary wraps a json library to decode the numbers of the interface{} values into
+// json.Number, see WithUseNumber.
+type numberLibrary struct {
+	JSONLibrary
+}
+
+func (l numberLibrary) Unmarshal(data []byte, v interface{}) error {
+	if lib, ok := l.JSONLibrary.(NumberJSONLibrary); ok {
+		return lib.UnmarshalUseNumber(data, v)
+	}
+	return NativeJSONLibrary{}.UnmarshalUseNumber(data, v)
+}
+
+// json-iterator and bytedance/sonic implementations live in their own modules, so that gloria does not
+// force those dependencies:
 //
-//func (l SonicLibrary) Unmarshal(data []byte, v interface{}) error {
-//	return sonic.Unmarshal(data, v)
-//}
+//	github.com/pokeyaro/gloria/codec/jsoniter
+//	github.com/pokeyaro/gloria/codec/sonic
+
+// jsonNode is a node of a json document whose object keys keep their order.
+type jsonNode struct {
+	delim byte        // '{' for an object, '[' for an array, 0 for a scalar
+	keys  []string    // keys of an object
+	items []*jsonNode // values of an object or an array
+	raw   []byte      // encoded scalar
+}
+
+// parseJSONNode reads the next value of the decoder as a jsonNode.
+func parseJSONNode(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	d, ok := tok.(json.Delim)
+	if !ok {
+		var raw []byte
+		if n, isNumber := tok.(json.Number); isNumber {
+			raw = []byte(n)
+		} else if raw, err = json.Marshal(tok); err != nil {
+			return nil, err
+		}
+		return &jsonNode{raw: raw}, nil
+	}
+
+	node := &jsonNode{delim: byte(d)}
+	for dec.More() {
+		if d == '{' {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			node.keys = append(node.keys, keyTok.(string))
+		}
+		item, err := parseJSONNode(dec)
+		if err != nil {
+			return nil, err
+		}
+		node.items = append(node.items, item)
+	}
+	// consume the closing delimiter
+	if _, err = dec.Token(); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// isEmptyValue reports whether the node is an empty value in the sense of the omitempty option:
+// null, false, 0, "", or an empty object or array.
+func (n *jsonNode) isEmptyValue() bool {
+	if n.delim != 0 {
+		return len(n.items) == 0
+	}
+
+	switch string(n.raw) {
+	case "null", "false", `""`:
+		return true
+	}
+	f, err := strconv.ParseFloat(string(n.raw), 64)
+	return err == nil && f == 0
+}
+
+// prune removes the fields of the objects which are null (omitNull) or empty (omitEmpty), recursively.
+func (n *jsonNode) prune(omitEmpty, omitNull bool) {
+	if n.delim == 0 {
+		return
+	}
+
+	keys, items := n.keys[:0], n.items[:0]
+	for i, item := range n.items {
+		item.prune(omitEmpty, omitNull)
+		if n.delim == '{' {
+			if omitNull && string(item.raw) == "null" || omitEmpty && item.isEmptyValue() {
+				continue
+			}
+			keys = append(keys, n.keys[i])
+		}
+		items = append(items, item)
+	}
+	if n.delim == '{' {
+		n.keys = keys
+	}
+	n.items = items
+}
+
+// encode writes the node to the buffer.
+func (n *jsonNode) encode(buf *bytes.Buffer) {
+	if n.delim == 0 {
+		buf.Write(n.raw)
+		return
+	}
+
+	buf.WriteByte(n.delim)
+	for i, item := range n.items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if n.delim == '{' {
+			key, _ := json.Marshal(n.keys[i])
+			buf.Write(key)
+			buf.WriteByte(':')
+		}
+		item.encode(buf)
+	}
+	if n.delim == '{' {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+}
+
+// pruneJSON removes the object fields of an encoded json document which are null (omitNull), or empty
+// (omitEmpty) like the omitempty option of encoding/json: null, false, 0, "", {} or []. It works on the
+// output of any JSONLibrary, and keeps the order of the remaining fields.
+func pruneJSON(data []byte, omitEmpty, omitNull bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	root, err := parseJSONNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	root.prune(omitEmpty, omitNull)
+
+	var buf bytes.Buffer
+	root.encode(&buf)
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"testing"
+)
+
+func TestPruneJSON(t *testing.T) {
+	in := `{"name":"a","age":0,"tags":[],"parent":null,"meta":{"x":null,"ok":false},"list":[null,0],"big":12345678901234567890}`
+
+	tests := []struct {
+		omitEmpty, omitNull bool
+		want                string
+	}{
+		{false, true, `{"name":"a","age":0,"tags":[],"meta":{"ok":false},"list":[null,0],"big":12345678901234567890}`},
+		{true, false, `{"name":"a","list":[null,0],"big":12345678901234567890}`},
+	}
+	for _, tt := range tests {
+		got, err := pruneJSON([]byte(in), tt.omitEmpty, tt.omitNull)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("pruneJSON(%v, %v) = %s, %v, want %s", tt.omitEmpty, tt.omitNull, got, err, tt.want)
+		}
+	}
+}
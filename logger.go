@@ -11,9 +11,18 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
 	"time"
 )
 
+// defaultRedactHeaders are the headers whose values are masked wherever headers are printed, see
+// WithRedactHeaders.
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// redactedValue replaces the values of the redacted headers.
+const redactedValue = "***"
+
 type level string
 
 // Log levels
@@ -64,6 +73,9 @@ const (
 	logColorSign = "\033[90m" // Light Gray
 )
 
+// logLevelWidth is the width of the longest log level text, used to align the log columns.
+const logLevelWidth = len(LogLevelSuccess)
+
 // ANSIColorCode returns the ANSI color code associated with the log level.
 func (l level) ANSIColorCode() string {
 	var LogColor = map[level]string{
@@ -79,8 +91,11 @@ func (l level) ANSIColorCode() string {
 
 // loggedTransport is custom Transport that logs request information.
 type loggedTransport struct {
-	transport http.RoundTripper
-	logger    *log.Logger
+	transport     http.RoundTripper
+	logger        *log.Logger
+	color         bool
+	slowThreshold time.Duration
+	requestID     string // header name of the request id, logged when not empty
 }
 
 // RoundTrip implements the RoundTrip method of the http.RoundTripper interface.
@@ -91,33 +106,58 @@ func (t *loggedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	duration := time.Since(startTime)
 
 	// Select log level based on request duration
+	threshold := t.slowThreshold
+	if threshold <= 0 {
+		threshold = TimeoutShort
+	}
 	logLevel := LogLevelSuccess
-	if duration > TimeoutShort {
+	if duration > threshold {
 		logLevel = LogLevelWarn
 	}
 
-	// Record request log
-	consoleLog(t.logger, logLevel, response.StatusCode, req.Method, req.URL.String(), fmt.Sprintf("Request took %s", duration))
+	// Correlate the log line with the server logs
+	var idText string
+	if !isEmptyString(t.requestID) {
+		if id := req.Header.Get(t.requestID); !isEmptyString(id) {
+			idText = fmt.Sprintf(" [%s: %s]", t.requestID, id)
+		}
+	}
+
+	// Record request log, the response is nil when the transport fails (such as dns or dial errors)
+	if err != nil || response == nil {
+		consoleLog(t.logger, t.color, LogLevelPanic, 0, req.Method, req.URL.String(), fmt.Sprintf("Request failed after %s: %v%s", duration, err, idText))
+		return response, err
+	}
+	consoleLog(t.logger, t.color, logLevel, response.StatusCode, req.Method, req.URL.String(), fmt.Sprintf("Request took %s%s", duration, idText))
 
 	return response, err
 }
 
 // sign returns a signature string for the generated content.
-func sign() string {
+// The 'color' parameter indicates whether ANSI escape codes are applied.
+func sign(color bool) string {
+	if !color {
+		return fmt.Sprintf("  # generate by %s.", Title)
+	}
 	return fmt.Sprintf("%s   # generate by %s.%s", logColorSign, Title, logColorReset)
 }
 
-// levelText returns the formatted text representation of the log level.
-// It applies the corresponding ANSI color code to the level text.
-func levelText(l level) string {
+// levelText returns the formatted text representation of the log level, right-aligned to the
+// width of the longest level.
+// It applies the corresponding ANSI color code to the level text when 'color' is true.
+func levelText(l level, color bool) string {
+	padding := strings.Repeat(" ", logLevelWidth-len(l))
+	if !color {
+		return fmt.Sprintf("%s[%s]", padding, l)
+	}
 	logColorStart := l.ANSIColorCode()
-	return fmt.Sprintf("%s[%s]%s", logColorStart, l, logColorReset)
+	return fmt.Sprintf("%s%s[%s]%s", padding, logColorStart, l, logColorReset)
 }
 
 // consoleLog is an auxiliary function that outputs log information with
 // a level prefix according to the log level and color.
-func consoleLog(logger *log.Logger, level level, statusCode int, method, url, message string) {
-	logger.Printf("| %20s | %18s | [%d] [%s] %s | %s %s", fileLocation(2), levelText(level), statusCode, method, url, message, sign())
+func consoleLog(logger *log.Logger, color bool, level level, statusCode int, method, url, message string) {
+	logger.Printf("| %20s | %s | [%d] [%s] %s | %s %s", fileLocation(2), levelText(level, color), statusCode, method, url, message, sign(color))
 }
 
 // fileLocation returns the file location in the format "filename:line",
@@ -139,16 +179,20 @@ func fileLocation(depth int) string {
 }
 
 // ChalkObj writes a log entry with the specified level and object value.
+// Like the other Chalk methods, it does nothing if the client has no logger.
 // It uses reflection to extract the value from the object parameter.
 // The 'level' parameter represents the log level.
 // The 'obj' parameter is the object to be logged.
 // It returns the updated Client instance.
 func (c *Client[T]) ChalkObj(level level, obj any) *Client[T] {
+	if c.Config.Logger == nil {
+		return c
+	}
 	v := reflect.ValueOf(obj)
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
-	c.Config.Logger.Printf("| %20s | %18s | %#v\n", fileLocation(3), levelText(level), v.Interface())
+	c.Config.Logger.Printf("| %20s | %s | %#v\n", fileLocation(3), levelText(level, c.Config.Color), v.Interface())
 	return c
 }
 
@@ -157,7 +201,10 @@ func (c *Client[T]) ChalkObj(level level, obj any) *Client[T] {
 // The 's' parameter is the string to be logged.
 // It returns the updated Client instance.
 func (c *Client[T]) ChalkStr(level level, s string) *Client[T] {
-	c.Config.Logger.Printf("| %20s | %18s | %s\n", fileLocation(3), levelText(level), s)
+	if c.Config.Logger == nil {
+		return c
+	}
+	c.Config.Logger.Printf("| %20s | %s | %s\n", fileLocation(3), levelText(level, c.Config.Color), s)
 	return c
 }
 
@@ -166,7 +213,10 @@ func (c *Client[T]) ChalkStr(level level, s string) *Client[T] {
 // The 'n' parameter is the integer to be logged.
 // It returns the updated Client instance.
 func (c *Client[T]) ChalkInt(level level, n int) *Client[T] {
-	c.Config.Logger.Printf("| %20s | %18s | %d\n", fileLocation(3), levelText(level), n)
+	if c.Config.Logger == nil {
+		return c
+	}
+	c.Config.Logger.Printf("| %20s | %s | %d\n", fileLocation(3), levelText(level, c.Config.Color), n)
 	return c
 }
 
@@ -176,11 +226,48 @@ func (c *Client[T]) ChalkInt(level level, n int) *Client[T] {
 // The 'args' parameter contains the arguments to be formatted.
 // It returns the updated Client instance.
 func (c *Client[T]) ChalkPrintf(level level, format string, args ...any) *Client[T] {
+	if c.Config.Logger == nil {
+		return c
+	}
 	message := fmt.Sprintf(format, args...)
 	if (level != LogLevelFail && level != LogLevelPanic) || isEmpty(c.Exception.CodeLocation) {
-		c.Config.Logger.Printf("| %20s | %18s | %s\n", fileLocation(3), levelText(level), message)
+		c.Config.Logger.Printf("| %20s | %s | %s\n", fileLocation(3), levelText(level, c.Config.Color), message)
 	} else {
-		c.Config.Logger.Printf("| %20s | %18s | %s\n", c.Exception.CodeLocation, levelText(level), message)
+		c.Config.Logger.Printf("| %20s | %s | %s\n", c.Exception.CodeLocation, levelText(level, c.Config.Color), message)
 	}
 	return c
 }
+
+// redactHeaders returns a copy of the headers h, with the values of the default redacted headers and
+// of the extra ones replaced by "***", the header names are case-insensitive.
+func redactHeaders(h http.Header, extra []string) http.Header {
+	redacted := h.Clone()
+	for _, keys := range [][]string{defaultRedactHeaders, extra} {
+		for _, k := range keys {
+			if values := redacted.Values(k); len(values) > 0 {
+				masked := make([]string, len(values))
+				for i := range masked {
+					masked[i] = redactedValue
+				}
+				redacted[http.CanonicalHeaderKey(k)] = masked
+			}
+		}
+	}
+	return redacted
+}
+
+// formatHeaders renders the headers h on a single line, sorted by name, such as
+// "Accept: */*; Authorization: ***".
+func formatHeaders(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s: %s", k, strings.Join(h[k], ", ")))
+	}
+	return strings.Join(fields, "; ")
+}
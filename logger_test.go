@@ -0,0 +1,137 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChalkStr_NoColor(t *testing.T) {
+	var buf bytes.Buffer
+
+	c := New[H]().Optional(Lambda[H](func(c *Client[H]) {
+		c.Config.Logger = log.New(&buf, "", 0)
+	}))
+	c.ChalkStr(LogLevelInfo, "hello")
+
+	if strings.Contains(buf.String(), "\u001B[") {
+		t.Errorf("log output should not contain escape codes: %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "|    [INFO] | hello") {
+		t.Errorf("unexpected log output: %q", buf.String())
+	}
+}
+
+func TestLevelText_Color(t *testing.T) {
+	got := levelText(LogLevelWarn, true)
+	want := "   " + logColorWarn + "[WARN]" + logColorReset
+	if got != want {
+		t.Errorf("levelText = %q, want %q", got, want)
+	}
+}
+
+func TestLoggedTransport_NilResponse(t *testing.T) {
+	var buf bytes.Buffer
+
+	c := New[H]().Optional(Lambda[H](func(c *Client[H]) {
+		c.Config.Logger = log.New(&buf, "", 0)
+	}))
+	// Nothing listens on port 1, so the dial fails and the transport returns a nil response.
+	c.SetRequest(MethodGet, "http://127.0.0.1:1/ping").Send()
+
+	if c.Exception.PanicError == nil {
+		t.Fatal("expected the network error to be recorded")
+	}
+	if !strings.Contains(buf.String(), "[0] [GET]") {
+		t.Errorf("unexpected log output: %q", buf.String())
+	}
+}
+
+func TestLoggedTransport_SlowThreshold(t *testing.T) {
+	var buf bytes.Buffer
+
+	srv := newTestServer(t, 200, `{"code":0,"msg":"ok"}`)
+	c := New[H]().Optional(
+		WithSlowThreshold[H](time.Nanosecond),
+		Lambda[H](func(c *Client[H]) {
+			c.Config.Logger = log.New(&buf, "", 0)
+		}),
+	)
+	c.SetRequest(MethodGet, srv.URL+"/ping").Send()
+
+	if !strings.Contains(buf.String(), "[WARN]") {
+		t.Errorf("slow request should be logged at WARN level: %q", buf.String())
+	}
+}
+
+func TestLoggedTransport_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	var got string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(HeaderRequestIDKey)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().Optional(
+		WithRequestID[H](""),
+		Lambda[H](func(c *Client[H]) {
+			c.Config.Logger = log.New(&buf, "", 0)
+		}),
+	).SetRequest(MethodGet, srv.URL).Send()
+
+	if len(c.RequestID()) != 36 || got != c.RequestID() {
+		t.Fatalf("request id = %q, sent %q", c.RequestID(), got)
+	}
+	if !strings.Contains(buf.String(), "[X-Request-Id: "+got+"]") {
+		t.Errorf("unexpected log output: %q", buf.String())
+	}
+
+	c.Reset().SendWith(WithRequestHeaders(H{HeaderRequestIDKey: "fixed"}))
+	if got != "fixed" || c.RequestID() != "fixed" {
+		t.Errorf("request id = %q, sent %q, want the preset one", c.RequestID(), got)
+	}
+}
+
+func TestRedactHeaders_DebugDump(t *testing.T) {
+	var buf bytes.Buffer
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H](WithIsDebug[H](true), WithRedactHeaders[H]("x-signature")).Optional(Lambda[H](func(c *Client[H]) {
+		c.Config.Logger = log.New(&buf, "", 0)
+	}))
+	c.SetRequest(MethodGet, srv.URL).
+		SetBearerAuth("token").
+		SetHeaders(H{"X-Api-Key": "key", "X-Signature": "sig", "X-Org": "acme"}).
+		Send()
+
+	out := buf.String()
+	for _, secret := range []string{"token", "key", "sig", "s3cr3t"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("debug output leaks %q: %s", secret, out)
+		}
+	}
+	for _, want := range []string{"Authorization: ***", "X-Signature: ***", "Set-Cookie: ***", "X-Org: acme"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("debug output does not contain %q: %s", want, out)
+		}
+	}
+
+	if got := c.Context.Request.Header.Get(HeaderAuthorizationKey); got != "Bearer token" {
+		t.Errorf("the sent header is redacted: %q", got)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// MockTransport is an http.RoundTripper which serves canned responses without network, so that the
+// code using gloria can be tested without a live server. Plug it with WithTransport.
+//
+// Example usage:
+//
+//	mock := gloria.NewMockTransport()
+//	mock.On(gloria.MethodGet, "/users/:id").Respond(http.StatusOK, gloria.H{"code": 0, "data": user})
+//	c := gloria.New[User]().Optional(gloria.WithTransport[User](mock))
+type MockTransport struct {
+	mu     sync.Mutex
+	routes []*MockRoute
+}
+
+// MockRoute is a route of a MockTransport, created by the On method.
+type MockRoute struct {
+	mu       *sync.Mutex // the mutex of the transport
+	method   string
+	segments []string
+	absolute bool
+
+	status int
+	body   []byte
+	header http.Header
+	calls  int
+}
+
+// NewMockTransport creates an empty MockTransport, a request which matches no route fails.
+func NewMockTransport() *MockTransport {
+	return &MockTransport{}
+}
+
+// On registers a route matching the requests of the method whose url matches urlPattern.
+// The urlPattern is either a path, such as "/users/:id", or an absolute url, such as
+// "https://example.org/users/:id". A ":name" segment matches any non-empty segment, and the query
+// string is ignored. The routes are matched in registration order.
+func (m *MockTransport) On(method, urlPattern string) *MockRoute {
+	route := &MockRoute{
+		mu:       &m.mu,
+		method:   strings.ToUpper(method),
+		absolute: strings.Contains(urlPattern, "://"),
+		status:   http.StatusOK,
+		header:   http.Header{HeaderContentTypeKey: {JsonContentType}},
+	}
+	route.segments = strings.Split(strings.TrimSuffix(urlPattern, signSlash), signSlash)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = append(m.routes, route)
+
+	return route
+}
+
+// Respond sets the response of the route. The body is sent as is when it is a string or a []byte,
+// and is encoded as json otherwise, the response Content-Type is application/json by default.
+func (r *MockRoute) Respond(status int, body any) *MockRoute {
+	r.status = status
+
+	switch v := body.(type) {
+	case nil:
+		r.body = nil
+	case string:
+		r.body = []byte(v)
+	case []byte:
+		r.body = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Errorf("mock response body: %w", err))
+		}
+		r.body = b
+	}
+
+	return r
+}
+
+// Header sets a header of the response of the route, such as the Content-Type.
+func (r *MockRoute) Header(key, value string) *MockRoute {
+	r.header.Set(key, value)
+
+	return r
+}
+
+// Calls returns the number of requests served by the route.
+func (r *MockRoute) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.calls
+}
+
+// match reports whether the route matches the request.
+func (r *MockRoute) match(req *http.Request) bool {
+	if r.method != req.Method {
+		return false
+	}
+
+	target := req.URL.EscapedPath()
+	if r.absolute {
+		target = fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, target)
+	}
+	segments := strings.Split(strings.TrimSuffix(target, signSlash), signSlash)
+	if len(segments) != len(r.segments) {
+		return false
+	}
+
+	for i, seg := range r.segments {
+		if strings.HasPrefix(seg, signColon) {
+			if isEmpty(segments[i]) {
+				return false
+			}
+			continue
+		}
+		if seg != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, route := range m.routes {
+		if !route.match(req) {
+			continue
+		}
+		route.calls++
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", route.status, http.StatusText(route.status)),
+			StatusCode:    route.status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        route.header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(route.body)),
+			ContentLength: int64(len(route.body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no mock route for %s %s", req.Method, req.URL)
+}
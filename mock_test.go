@@ -0,0 +1,31 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMockTransport(t *testing.T) {
+	mock := NewMockTransport()
+	user := mock.On(MethodGet, "/users/:id").Respond(http.StatusOK, H{"code": 0, "data": H{"id": 7}})
+	mock.On(MethodDelete, "https://api.example.org/users/:id").Respond(http.StatusNotFound, `{"code":404,"msg":"no such user"}`)
+
+	c := New[H](WithTransport[H](mock)).SetRequest(MethodGet, "https://api.example.org/users/7?verbose=1").Send()
+	if !isEmpty(c.Exception) || c.Data()["id"] != float64(7) || user.Calls() != 1 {
+		t.Fatalf("data = %v, calls = %d, exception %+v", c.Data(), user.Calls(), c.Exception)
+	}
+
+	c = New[H](WithTransport[H](mock)).SetRequest(MethodDelete, "https://api.example.org/users/7").Send()
+	if c.Exception.StatusError == nil || c.Exception.StatusError.StatusCode != http.StatusNotFound {
+		t.Errorf("exception = %+v, want a 404 status error", c.Exception)
+	}
+
+	c = New[H](WithTransport[H](mock)).SetRequest(MethodGet, "https://api.example.org/users").Send()
+	if c.Exception.Kind != KindNetwork {
+		t.Errorf("exception = %+v, want an unmatched route error", c.Exception)
+	}
+}
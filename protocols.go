@@ -0,0 +1,33 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build go1.24
+
+package gloria
+
+import (
+	"net/http"
+)
+
+// h2cSupported reports whether cleartext HTTP/2 (h2c) is supported, which requires Go 1.24.
+const h2cSupported = true
+
+// configureProtocols sets the protocols of the transport according to the configuration.
+// By default the transport negotiates HTTP/2 over TLS and falls back to HTTP/1.1.
+// With Config.HTTP2, HTTP/1.1 is disabled, and with Config.H2C, http:// URLs use cleartext HTTP/2
+// with prior knowledge.
+func configureProtocols(tr *http.Transport, cfg *Config) {
+	// A custom TLSClientConfig disables HTTP/2 unless it is forced
+	tr.ForceAttemptHTTP2 = true
+	if !cfg.HTTP2 && !cfg.H2C {
+		return
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	if cfg.H2C {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	tr.Protocols = protocols
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build go1.24
+
+package gloria
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSend_H2C(t *testing.T) {
+	srv := newProtoServer(t)
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetHTTP1(true)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+
+	c := New[H]().SetRequest(MethodGet, srv.URL).Send()
+	if !isEmpty(c.Exception) || c.Result.Msg != "HTTP/1.1" {
+		t.Fatalf("proto = %q, exception %+v", c.Result.Msg, c.Exception)
+	}
+
+	c = New[H]().Optional(WithForceHTTP2[H](true)).SetRequest(MethodGet, srv.URL).Send()
+	if !isEmpty(c.Exception) || c.Result.Msg != "HTTP/2.0" {
+		t.Errorf("proto = %q, exception %+v", c.Result.Msg, c.Exception)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.24
+
+package gloria
+
+import (
+	"net/http"
+)
+
+// h2cSupported reports whether cleartext HTTP/2 (h2c) is supported, which requires Go 1.24.
+const h2cSupported = false
+
+// configureProtocols sets the protocols of the transport according to the configuration.
+// Before Go 1.24 the transport cannot disable HTTP/1.1, so HTTP/2 is only attempted over TLS.
+func configureProtocols(tr *http.Transport, cfg *Config) {
+	// A custom TLSClientConfig disables HTTP/2 unless it is forced
+	tr.ForceAttemptHTTP2 = true
+}
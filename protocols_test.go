@@ -0,0 +1,40 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newProtoServer returns an unstarted local server that replies with the protocol of the request.
+func newProtoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0,"msg":"` + r.Proto + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestSend_HTTP2(t *testing.T) {
+	srv := newProtoServer(t)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+
+	for _, force := range []bool{false, true} {
+		c := New[H]().Optional(WithSkipTLS[H](true))
+		if force {
+			c.Optional(WithForceHTTP2[H](false))
+		}
+		c.SetRequest(MethodGet, srv.URL).Send()
+		if !isEmpty(c.Exception) || c.Result.Msg != "HTTP/2.0" {
+			t.Errorf("force = %v: proto = %q, exception %+v", force, c.Result.Msg, c.Exception)
+		}
+	}
+}
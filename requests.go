@@ -5,9 +5,214 @@
 package gloria
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"reflect"
+	"strings"
+	"sync"
 )
 
+/*
+	The package-level default options of the request shorthands
+*/
+
+var (
+	defaultOptionsMu sync.RWMutex
+	defaultOptions   []ClientFunc[any]
+)
+
+// Configure sets the package-level default options applied to every request created by the
+// shorthand functions (GET, POST, PUT, DELETE, PATCH, HEAD, OPTIONS and Request).
+// The options are applied right after the Default template is built, so they override its
+// presets, and each call replaces the options of the previous call.
+//
+// The options are applied to the settings shared by all client types: the Config, the headers,
+// the authorization, the query parameters and the body transforms (such as WithBodyDecryptor).
+// The pre and post hooks registered by the options, which are typed for Client[any], are not kept.
+//
+// Example usage:
+//
+//	gloria.Configure(
+//		gloria.WithTimeout[any](gloria.TimeoutShort),
+//		gloria.WithSkipTLS[any](false),
+//	)
+func Configure(opts ...ClientFunc[any]) {
+	defaultOptionsMu.Lock()
+	defer defaultOptionsMu.Unlock()
+
+	defaultOptions = opts
+}
+
+// applyDefaultOptions applies the package-level default options to the client instance.
+// Since the options are typed for Client[any], they are applied to a template client sharing
+// the settings of the client instance, and the settings are copied back afterward.
+func applyDefaultOptions[T any](c *Client[T]) {
+	defaultOptionsMu.RLock()
+	opts := defaultOptions
+	defaultOptionsMu.RUnlock()
+
+	applyAnyOptions(c, opts)
+}
+
+// applyAnyOptions applies options typed for Client[any] to the client instance, through a template
+// client sharing the settings of the client instance.
+func applyAnyOptions[T any](c *Client[T], opts []ClientFunc[any]) {
+	if len(opts) == 0 {
+		return
+	}
+
+	tmpl := New[any]()
+	*tmpl.Config = *c.Config
+	tmpl.Exception = c.Exception
+	tmpl.buildErr = c.buildErr
+	tmpl.params = c.params
+	tmpl.authorization = c.authorization
+	tmpl.headers = c.headers
+	tmpl.transforms = c.transforms
+
+	tmpl.Optional(opts...)
+
+	*c.Config = *tmpl.Config
+	c.Exception = tmpl.Exception
+	c.buildErr = tmpl.buildErr
+	c.params = tmpl.params
+	c.authorization = tmpl.authorization
+	c.headers = tmpl.headers
+	c.transforms = tmpl.transforms
+}
+
+/*
+	The per-host default options
+*/
+
+var (
+	hostOptionsMu sync.RWMutex
+	hostOptions   = map[string][]ClientFunc[any]{}
+)
+
+// RegisterHost registers default options for the requests to a host, such as its timeout or its
+// default headers, so that an application calling several services does not configure each client.
+// The host is matched against the host of the request, with or without the port, such as
+// "api.example.org" or "api.example.org:8443", the exact match taking precedence.
+//
+// The options are applied once to a client created by Default (or a request shorthand), when its host
+// is resolved (such as by SetRequest), on top of its presets. The settings of the client which differ
+// from its presets, such as the ones of its own options, take precedence over the host options, and
+// the default headers are merged key by key. Switching the client to another host replaces the host
+// options which are still in effect.
+// Only the Config settings of the options are applied: it panics if an option registers a hook or a
+// body transform (such as WithBodyDecryptor), which is not supported.
+// Each call replaces the options of the host, and calling it without options unregisters the host.
+//
+// Example usage:
+//
+//	gloria.RegisterHost("billing.internal",
+//		gloria.WithTimeout[any](gloria.TimeoutLong),
+//		gloria.WithDefaultHeaders[any](gloria.H{"X-Api-Key": key}),
+//	)
+func RegisterHost(host string, opts ...ClientFunc[any]) {
+	hostOptionsMu.Lock()
+	defer hostOptionsMu.Unlock()
+
+	if len(opts) == 0 {
+		delete(hostOptions, host)
+		return
+	}
+
+	probe := New[any]().Optional(opts...)
+	if len(probe.beforeRequest) > 0 || len(probe.afterResponse) > 0 || len(probe.transforms) > 0 {
+		panic("RegisterHost: the options must not register hooks or body transforms.")
+	}
+	hostOptions[host] = opts
+}
+
+// hostDefaults holds the state of the options registered by RegisterHost for a client instance.
+type hostDefaults struct {
+	host    string // the host whose options are in effect
+	preset  Config // the settings of the client which the host options are applied onto
+	applied Config // the settings resulting from the host options
+}
+
+// newHostDefaults enables the options registered by RegisterHost for a client instance, on top of
+// its current settings.
+func newHostDefaults(cfg *Config) *hostDefaults {
+	return &hostDefaults{preset: *cfg, applied: *cfg}
+}
+
+// applyHostOptions applies the options registered for the host of the client instance, once per
+// host, if they are enabled (see Default). The settings changed by the caller since the presets are
+// kept, and the ones of the options of a previous host are replaced.
+func applyHostOptions[T any](c *Client[T], host string) {
+	d := c.hostDefaults
+	if d == nil || d.host == host {
+		return
+	}
+	d.host = host
+
+	applied := d.preset
+	if opts := lookupHostOptions(host); len(opts) > 0 {
+		tmpl := New[any]()
+		*tmpl.Config = d.preset
+		tmpl.Optional(opts...)
+		applied = *tmpl.Config
+	}
+
+	// revert the settings of the previous host, then apply the ones of this host
+	replaceUnchanged(reflect.ValueOf(c.Config).Elem(), reflect.ValueOf(&d.applied).Elem(), reflect.ValueOf(&d.preset).Elem())
+	replaceUnchanged(reflect.ValueOf(c.Config).Elem(), reflect.ValueOf(&d.preset).Elem(), reflect.ValueOf(&applied).Elem())
+	d.applied = applied
+}
+
+// replaceUnchanged sets the exported fields of the struct dst which are equal to the ones of from to
+// the ones of to. The maps (such as DefaultHeaders) are compared and replaced key by key.
+func replaceUnchanged(dst, from, to reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		if !dst.Type().Field(i).IsExported() {
+			continue
+		}
+		d, f, t := dst.Field(i), from.Field(i), to.Field(i)
+		if d.Kind() != reflect.Map {
+			if reflect.DeepEqual(d.Interface(), f.Interface()) {
+				d.Set(t)
+			}
+			continue
+		}
+
+		// the maps are copied on write, so that the maps of from and to are left unchanged
+		merged := reflect.MakeMap(d.Type())
+		for _, k := range d.MapKeys() {
+			merged.SetMapIndex(k, d.MapIndex(k))
+		}
+		keys := append(f.MapKeys(), t.MapKeys()...)
+		for _, k := range keys {
+			dv, fv := d.MapIndex(k), f.MapIndex(k)
+			if dv.IsValid() != fv.IsValid() || dv.IsValid() && !reflect.DeepEqual(dv.Interface(), fv.Interface()) {
+				continue
+			}
+			// an invalid value deletes the key
+			merged.SetMapIndex(k, t.MapIndex(k))
+		}
+		if merged.Len() > 0 || !d.IsNil() {
+			d.Set(merged)
+		}
+	}
+}
+
+// lookupHostOptions returns the options registered for the host of a request, which may hold a port.
+func lookupHostOptions(host string) []ClientFunc[any] {
+	hostOptionsMu.RLock()
+	defer hostOptionsMu.RUnlock()
+
+	if opts, ok := hostOptions[host]; ok {
+		return opts
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return hostOptions[strings.Trim(hostname, "[]")]
+	}
+	return nil
+}
+
 /*
 	The following is the request method
 */
@@ -18,12 +223,12 @@ import (
 //
 // The function performs the following steps:
 // 1. Validates the method to ensure it is a valid HTTP method.
-// 2. Parses the URL segments from the path.
-// 3. Initializes a new client instance using the default settings.
+// 2. Initializes a new client instance using the default settings and the options set by Configure.
+// 3. Parses the URL segments from the path, resolved against the base URL if it is relative.
 // 4. Sets the request method for the client.
 // 5. Sets the URL for the client based on the parsed URL segments.
-// 6. Sets the query parameters for the client, unless the method is OPTIONS.
-// 7. Sets the request payload (body) for the client, unless the method is GET or OPTIONS.
+// 6. Sets the query parameters for the client (both the url query and params), unless the method is OPTIONS.
+// 7. Sets the request payload (body) for the client, unless it is nil or "" or the method has no body (see WithAllowGetBody).
 // 8. Sets the request headers for the client.
 // 9. Sends the request using the client.
 //
@@ -32,11 +237,13 @@ func request[T any](method, path string, params H, data any, headers ...H) *Clie
 	// Check if the method is valid
 	isValidMethod(method)
 
-	// Parse the URL
-	parseUrl := urlSegments(path)
-
 	// Initialize a new client
 	r := Default[T]()
+	applyDefaultOptions(r)
+	r.hostDefaults = newHostDefaults(r.Config)
+
+	// Parse the URL, a relative path is resolved against the base URL
+	parseUrl := urlSegments(r.resolveURL(path))
 
 	// Set the request method
 	r.SetMethod(method)
@@ -44,22 +251,17 @@ func request[T any](method, path string, params H, data any, headers ...H) *Clie
 	// Set the URL
 	r.SetURL(parseUrl.scheme, parseUrl.host, parseUrl.baseURI, parseUrl.endpoint)
 
-	// Set the query parameters
+	// Set the query parameters, the params argument takes precedence over the url query
 	if method != MethodOptions {
-		if isEmpty(parseUrl.params) {
-			r.SetQueryParams(params)
-		} else {
-			r.SetQueryParams(parseUrl.params)
-		}
+		r.SetQueryParams(parseUrl.params)
+		r.SetQueryParams(params)
 	}
 
-	// Set the request payload
-	if method != MethodGet && method != MethodOptions {
-		if isEmpty(data) {
-			r.SetPayload(nil)
-		} else {
-			r.SetPayload(data)
-		}
+	// Set the request payload, a GET request has a body only if allowed (see WithAllowGetBody), and
+	// nil, an empty string or the Placeholder is no payload, while a zero value (such as H{}) is sent
+	hasBody := method != MethodOptions && method != MethodHead && (method != MethodGet || r.Config.AllowGetBody)
+	if hasBody && data != Placeholder && data != "" && !isNil(data) {
+		r.SetPayload(data)
 	}
 
 	// Set the request headers
@@ -141,3 +343,65 @@ func Request[T any](path string, params H, data any, headers ...H) ExecMethod[T]
 		return request[T](method, path, params, data, headers...).Result
 	}
 }
+
+type ExecMethodE[T any] func(method string) (*RESTFulResp[T], error)
+
+// RequestE is like Request, but the ExecMethodE function it returns also surfaces the error of the
+// request, as composed by Try (such as a network failure, an unexpected status or a business failure),
+// which Request discards. The restful response is returned even on error, for its code and message.
+//
+// Example usage:
+//
+//	resp, err := gloria.RequestE[User]("https://example.com/users/7", nil, nil)(gloria.MethodGet)
+//	if err != nil {
+//		// handle error
+//	}
+func RequestE[T any](path string, params H, data any, headers ...H) ExecMethodE[T] {
+	return func(method string) (*RESTFulResp[T], error) {
+		c := request[T](method, path, params, data, headers...)
+		_, err := c.Try()
+		return c.Result, err
+	}
+}
+
+/*
+	The custom request methods
+*/
+
+var (
+	customMethodsMu sync.RWMutex
+	customMethods   []string
+)
+
+// RegisterMethod registers a custom request method, such as the WebDAV "PROPFIND" or the cache "PURGE",
+// so that it is accepted besides the QueryMethods. The method is upper-cased, and it must be a valid
+// http token, otherwise RegisterMethod panics. Registering a method twice is a no-op.
+//
+// Example usage:
+//
+//	gloria.RegisterMethod("PURGE")
+//	c := gloria.New[any]().SetRequest("PURGE", "https://cdn.example.org/assets/app.js").Send()
+func RegisterMethod(method string) {
+	method = strings.ToUpper(method)
+	if !isToken(method) {
+		panic(fmt.Errorf("invalid request method %q", method))
+	}
+
+	customMethodsMu.Lock()
+	defer customMethodsMu.Unlock()
+
+	for _, m := range customMethods {
+		if m == method {
+			return
+		}
+	}
+	customMethods = append(customMethods, method)
+}
+
+// registeredMethods returns the QueryMethods followed by the custom methods of RegisterMethod.
+func registeredMethods() []string {
+	customMethodsMu.RLock()
+	defer customMethodsMu.RUnlock()
+
+	return append(append([]string(nil), QueryMethods...), customMethods...)
+}
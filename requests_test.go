@@ -0,0 +1,245 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	var gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Org")
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	Configure(
+		WithTimeout[any](TimeoutShort),
+		WithUseLogger[any](false),
+		Lambda[any](func(c *Client[any]) {
+			c.Config.Logger = nil
+			c.SetHeader("X-Org", "acme")
+		}),
+	)
+	defer Configure()
+
+	c := GET[H](srv.URL+"/ping", nil)
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if c.Config.Timeout != TimeoutShort || c.Config.Logger != nil {
+		t.Errorf("default options are not applied: %+v", c.Config)
+	}
+	if gotHeader != "acme" {
+		t.Errorf("X-Org header = %q, want %q", gotHeader, "acme")
+	}
+}
+
+func TestConfigure_BodyDecryptor(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, base64.StdEncoding.EncodeToString([]byte(`{"code":0,"data":{"id":1}}`)))
+
+	Configure(WithBodyDecryptor[any](func(b []byte) ([]byte, error) {
+		return base64.StdEncoding.DecodeString(string(b))
+	}))
+	defer Configure()
+
+	c := GET[H](srv.URL, nil)
+	if !isEmpty(c.Exception) || c.Data()["id"] != float64(1) {
+		t.Errorf("data = %v, exception = %+v, want the decrypted body", c.Data(), c.Exception)
+	}
+}
+
+func TestRegisterHost(t *testing.T) {
+	var gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-Api-Key")
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	RegisterHost("127.0.0.1",
+		WithTimeout[any](TimeoutLong),
+		WithDefaultHeaders[any](H{"X-Api-Key": "secret"}),
+	)
+	defer RegisterHost("127.0.0.1")
+
+	c := Default[H]().SetRequest(MethodGet, srv.URL+"/ping")
+	c.Config.Logger = nil
+	c.Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if c.Config.Timeout != TimeoutLong || gotKey != "secret" {
+		t.Errorf("timeout = %v, X-Api-Key = %q, want the host options", c.Config.Timeout, gotKey)
+	}
+
+	c = Default[H]().SetRequest(MethodGet, "http://localhost:1/ping")
+	c.Config.Logger = nil
+	c.Send()
+	if c.Config.Timeout != TimeoutMedium {
+		t.Errorf("timeout = %v, want the Default preset for an unregistered host", c.Config.Timeout)
+	}
+}
+
+func TestRegisterHost_ClientOptionsWin(t *testing.T) {
+	var gotOrg, gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOrg, gotKey = r.Header.Get("X-Org"), r.Header.Get("X-Api-Key")
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	RegisterHost("127.0.0.1",
+		WithTimeout[any](TimeoutLong),
+		WithRedactHeaders[any]("X-Api-Key"),
+		WithDefaultHeaders[any](H{"X-Api-Key": "secret", "X-Org": "host"}),
+	)
+	defer RegisterHost("127.0.0.1")
+
+	c := Default[H]().Optional(
+		WithTimeout[H](TimeoutShort),
+		WithDefaultHeaders[H](H{"X-Org": "client"}),
+	).SetRequest(MethodGet, srv.URL+"/ping")
+	c.Config.Logger = nil
+	for i := 0; i < 3; i++ {
+		c.Send()
+	}
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if c.Config.Timeout != TimeoutShort {
+		t.Errorf("timeout = %v, want the one of the client", c.Config.Timeout)
+	}
+	if gotOrg != "client" || gotKey != "secret" {
+		t.Errorf("X-Org = %q, X-Api-Key = %q, want the client header and the host one", gotOrg, gotKey)
+	}
+	if len(c.Config.RedactHeaders) != 1 {
+		t.Errorf("redact headers = %v, want the host options applied once", c.Config.RedactHeaders)
+	}
+
+	// the options of the previous host are replaced
+	c.SetRequest(MethodGet, "http://localhost:1/ping")
+	if _, ok := c.Config.DefaultHeaders["X-Api-Key"]; ok || len(c.Config.RedactHeaders) != 0 {
+		t.Errorf("config = %v %v, want the host options reverted", c.Config.DefaultHeaders, c.Config.RedactHeaders)
+	}
+	if c.Config.DefaultHeaders["X-Org"] != "client" || c.Config.Timeout != TimeoutShort {
+		t.Errorf("config = %v %v, want the client options kept", c.Config.DefaultHeaders, c.Config.Timeout)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("an option registering a body transform should panic")
+		}
+	}()
+	RegisterHost("127.0.0.1", WithBodyDecryptor[any](func(b []byte) ([]byte, error) { return b, nil }))
+}
+
+func TestRequestE(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"code":0,"msg":"ok","data":{"id":1}}`)
+
+	resp, err := RequestE[H](srv.URL+"/users/1", nil, nil)(MethodGet)
+	if err != nil || resp.Data["id"] != float64(1) {
+		t.Fatalf("RequestE() = %+v, %v", resp, err)
+	}
+
+	resp, err = RequestE[H]("http://127.0.0.1:1/users/1", nil, nil)(MethodGet)
+	if err == nil || resp == nil {
+		t.Errorf("RequestE() = %+v, %v, want a network error", resp, err)
+	}
+}
+
+func TestAllowGetBody(t *testing.T) {
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+		gotBody = string(bs)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	query := H{"query": H{"match_all": H{}}}
+	if Request[H](srv.URL+"/_search", nil, query)(MethodGet); gotBody != "" {
+		t.Errorf("body = %q, want no body by default", gotBody)
+	}
+
+	Configure(WithAllowGetBody[any](true))
+	defer Configure()
+
+	if Request[H](srv.URL+"/_search", nil, query)(MethodGet); gotBody != `{"query":{"match_all":{}}}` {
+		t.Errorf("body = %q, want the query", gotBody)
+	}
+	if GET[H](srv.URL+"/_search", nil); gotBody != "" {
+		t.Errorf("body = %q, want no body without payload", gotBody)
+	}
+}
+
+func TestRequest_NoPayload(t *testing.T) {
+	var gotLength int64
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+		gotLength, gotBody = r.ContentLength, string(bs)
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	if HEAD[H](srv.URL+"/users", nil); gotLength != 0 || gotBody != "" {
+		t.Errorf("HEAD body = %q (length %d), want no body", gotBody, gotLength)
+	}
+	if POST[H](srv.URL+"/users", nil, ""); gotLength != 0 || gotBody != "" {
+		t.Errorf("POST body = %q (length %d), want no body for an empty string", gotBody, gotLength)
+	}
+	if POST[H](srv.URL+"/users", nil, H{"name": "gloria"}); gotBody != `{"name":"gloria"}` {
+		t.Errorf("POST body = %q, want the payload", gotBody)
+	}
+
+	// a zero value is a payload
+	if POST[H](srv.URL+"/users", nil, H{}); gotBody != `{}` {
+		t.Errorf("POST body = %q, want {}", gotBody)
+	}
+	if POST[H](srv.URL+"/users", nil, struct {
+		A int `json:"a"`
+	}{}); gotBody != `{"a":0}` {
+		t.Errorf("POST body = %q, want {\"a\":0}", gotBody)
+	}
+}
+
+func TestRegisterMethod(t *testing.T) {
+	var gotMethod string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s does not panic", name)
+			}
+		}()
+		fn()
+	}
+
+	mustPanic("an unregistered method", func() { New[H]().SetMethod("PROPFIND") })
+	mustPanic("an invalid method", func() { RegisterMethod("PROP FIND") })
+
+	RegisterMethod("propfind")
+	RegisterMethod("PROPFIND")
+
+	c := New[H]().SetRequest("PROPFIND", srv.URL+"/files").Send()
+	if !isEmpty(c.Exception) || gotMethod != "PROPFIND" {
+		t.Errorf("method = %q, exception = %+v", gotMethod, c.Exception)
+	}
+	if n := len(registeredMethods()) - len(QueryMethods); n != 1 {
+		t.Errorf("%d custom methods, want 1", n)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stats is a collector of request durations, used to compute aggregate metrics across many
+// Send calls, such as in load testing. It is safe for concurrent use, so one collector can be
+// shared by many client instances through the WithStats function.
+type Stats struct {
+	mu        sync.Mutex
+	durations []time.Duration
+}
+
+// NewStats returns an empty collector.
+func NewStats() *Stats {
+	return &Stats{}
+}
+
+// Record adds a request duration to the collector.
+func (s *Stats) Record(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.durations = append(s.durations, d)
+}
+
+// Count returns the number of recorded durations.
+func (s *Stats) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.durations)
+}
+
+// Report returns the 50th, 95th and 99th percentiles (nearest-rank), the minimum and the maximum
+// of the recorded durations. All values are zero if nothing has been recorded.
+func (s *Stats) Report() (p50, p95, p99, min, max time.Duration) {
+	s.mu.Lock()
+	sorted := make([]time.Duration, len(s.durations))
+	copy(sorted, s.durations)
+	s.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return percentile(sorted, 50), percentile(sorted, 95), percentile(sorted, 99), sorted[0], sorted[len(sorted)-1]
+}
+
+// percentile returns the p-th percentile of the sorted durations with the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// RequestMetrics describes a completed Send call, successful or not, it is passed to the hook of
+// WithMetricsHook so that the metrics can be pushed to any backend (such as StatsD or Prometheus).
+type RequestMetrics struct {
+	Method    string        // http request method
+	URL       string        // full request url
+	Status    int           // http response status code, 0 if no response was received
+	Duration  time.Duration // duration of the Send call, including the retries and the decoding
+	BytesIn   int64         // size of the response body
+	BytesOut  int64         // size of the request body, -1 if it is streamed with an unknown length
+	Retries   int           // number of retries, 0 if the first attempt completed the request
+	ErrorKind ErrorKind     // kind of the recorded Exception, empty if the request succeeded
+}
+
+// reportMetrics calls the metrics hook of the client instance, if any, with the metrics of the Send
+// call started at start.
+func (c *Client[T]) reportMetrics(start time.Time) {
+	if c.Config.MetricsHook == nil {
+		return
+	}
+
+	m := RequestMetrics{
+		Method:    c.Meta.Method,
+		URL:       c.Meta.Url,
+		Duration:  time.Since(start),
+		Retries:   c.retries,
+		ErrorKind: c.Exception.Kind,
+	}
+
+	// the request and the response are those of a previous Send, unless a response was received
+	if c.Meta.ReceivedAt.Before(start) {
+		c.Config.MetricsHook(m)
+		return
+	}
+	if req := c.Context.Request; req.Body != nil && req.Body != http.NoBody {
+		m.BytesOut = req.ContentLength
+	}
+	if resp := c.Context.Response; resp != nil && resp.R != nil {
+		m.Status = resp.Status
+		m.BytesIn = resp.length
+	}
+
+	c.Config.MetricsHook(m)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStats_Report(t *testing.T) {
+	s := NewStats()
+	if p50, _, _, _, max := s.Report(); p50 != 0 || max != 0 {
+		t.Fatal("an empty collector should report zero values")
+	}
+
+	for i := 100; i >= 1; i-- {
+		s.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	p50, p95, p99, min, max := s.Report()
+	if p50 != 50*time.Millisecond || p95 != 95*time.Millisecond || p99 != 99*time.Millisecond {
+		t.Errorf("percentiles = %s, %s, %s", p50, p95, p99)
+	}
+	if min != time.Millisecond || max != 100*time.Millisecond {
+		t.Errorf("min = %s, max = %s", min, max)
+	}
+}
+
+func TestSend_WithStats(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, `{"code":0}`)
+	s := NewStats()
+
+	for i := 0; i < 3; i++ {
+		New[H]().Optional(WithStats[H](s)).SetRequest(MethodGet, srv.URL+"/ping").Send()
+	}
+	if s.Count() != 3 {
+		t.Errorf("count = %d, want 3", s.Count())
+	}
+}
+
+func TestWithMetricsHook(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0,"data":"ok"}`))
+	}))
+	defer srv.Close()
+
+	var got []RequestMetrics
+	hook := WithMetricsHook[string](func(m RequestMetrics) { got = append(got, m) })
+
+	c := New[string](hook, WithRetry[string](2, time.Millisecond)).
+		SetRequest(MethodPost, srv.URL+"/orders").SetPayload(H{"id": 1}).Send()
+	if !isEmpty(c.Exception) || len(got) != 1 {
+		t.Fatalf("metrics = %+v, exception = %+v", got, c.Exception)
+	}
+	m := got[0]
+	if m.Method != MethodPost || m.URL != srv.URL+"/orders" || m.Status != http.StatusOK || m.Retries != 1 ||
+		m.BytesIn != 22 || m.BytesOut != 8 || m.Duration <= 0 || m.ErrorKind != "" {
+		t.Errorf("metrics = %+v", m)
+	}
+
+	got = nil
+	New[string](hook).SetRequest(MethodGet, "http://127.0.0.1:1/down").Send()
+	if len(got) != 1 || got[0].ErrorKind != KindNetwork || got[0].Status != 0 {
+		t.Errorf("metrics = %+v, want a network failure", got)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// StreamJSON sends the request and reads the response body as newline-delimited json (NDJSON), each
+// non-blank line is unmarshaled into a fresh T with the configured JSONLoader and passed to fn, without
+// buffering the whole stream. The reading stops at the end of the stream, on the first error returned
+// by fn, which is returned as is, or once the request context is done.
+//
+// The request, status, decode and read failures are recorded in the Exception like Send, and returned
+// as the error of Try. Note that the Config.Timeout bounds the whole stream like any request.
+//
+// Example usage:
+//
+//	err := gloria.New[Event]().SetRequest(gloria.MethodGet, "https://example.com/events").
+//		StreamJSON(func(e Event) error {
+//			fmt.Println(e.Name)
+//			return nil
+//		})
+func (c *Client[T]) StreamJSON(fn func(T) error) error {
+	return c.stream(func(body io.Reader) error {
+		r := bufio.NewReader(body)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return c.recordStreamError(classifyError(err), err)
+			}
+
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				var v T
+				if errJson := c.jsonLib().Unmarshal(line, &v); errJson != nil {
+					return c.recordStreamError(KindDecode, errJson)
+				}
+				if errFn := fn(v); errFn != nil {
+					return errFn
+				}
+			}
+
+			if err == io.EOF {
+				return nil
+			}
+			if errCtx := c.Context.Request.Context().Err(); errCtx != nil {
+				return c.recordStreamError(classifyError(errCtx), errCtx)
+			}
+		}
+	})
+}
+
+// StreamSSE sends the request and reads the response body as server-sent events (text/event-stream),
+// each event is dispatched to fn with its type, "message" by default, and its data, the data lines of an
+// event are joined with a newline. Comments and events without data are skipped. The reading stops like
+// StreamJSON, at the end of the stream, on the first error returned by fn or once the request context
+// is done.
+//
+// Example usage:
+//
+//	err := gloria.New[any]().SetRequest(gloria.MethodGet, "https://example.com/sse").
+//		StreamSSE(func(event, data string) error {
+//			fmt.Println(event, data)
+//			return nil
+//		})
+func (c *Client[T]) StreamSSE(fn func(event, data string) error) error {
+	return c.stream(func(body io.Reader) error {
+		r := bufio.NewReader(body)
+		var (
+			event string
+			data  []string
+		)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return c.recordStreamError(classifyError(err), err)
+			}
+
+			line = strings.TrimRight(line, "\r\n")
+			switch field, value := parseSSELine(line); {
+			case isEmptyString(line):
+				// a blank line dispatches the event
+				if len(data) > 0 {
+					if isEmptyString(event) {
+						event = "message"
+					}
+					if errFn := fn(event, strings.Join(data, "\n")); errFn != nil {
+						return errFn
+					}
+				}
+				event, data = "", nil
+			case field == "event":
+				event = value
+			case field == "data":
+				data = append(data, value)
+			}
+
+			// an incomplete event at the end of the stream is discarded
+			if err == io.EOF {
+				return nil
+			}
+			if errCtx := c.Context.Request.Context().Err(); errCtx != nil {
+				return c.recordStreamError(classifyError(errCtx), errCtx)
+			}
+		}
+	})
+}
+
+// parseSSELine splits a line of an event stream into its field and value, the single space following
+// the colon is not part of the value. A comment line, starting with a colon, has an empty field.
+func parseSSELine(line string) (field, value string) {
+	field, value, found := strings.Cut(line, signColon)
+	if !found {
+		return line, ""
+	}
+	return field, strings.TrimPrefix(value, " ")
+}
+
+// stream sends the request and hands the unread response body to read, instead of buffering it like
+// Send. A non-2xx response is recorded as a KindStatus Exception, with a snippet of the body, and read
+// is not called.
+func (c *Client[T]) stream(read func(body io.Reader) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, cancel := c.roundTrip()
+	if resp == nil {
+		_, err := c.Try()
+		return err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	c.Context.Response = &Response{
+		R:      resp,
+		Status: resp.StatusCode,
+	}
+
+	if !c.IsSuccessStatus() {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, bodySnippetSize))
+		c.Context.Response.bs = body
+		c.Context.Response.text = string(body)
+		c.Context.Response.length = int64(len(body))
+		c.recordUndecodableStatus()
+		_, err := c.Try()
+		return err
+	}
+
+	return read(resp.Body)
+}
+
+// recordStreamError records an Exception of the kind for a failure while reading a stream, and returns
+// the error.
+func (c *Client[T]) recordStreamError(kind ErrorKind, err error) error {
+	c.Exception = &Exception{
+		CodeLocation:   fileLocation(2),
+		Kind:           kind,
+		PanicError:     err,
+		OccurrenceTime: time.Now().Unix(),
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type streamEvent struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestStreamJSON(t *testing.T) {
+	got := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":1,"name":"created"}` + "\n\n"))
+		w.(http.Flusher).Flush()
+		<-got // the first event is handled before the stream ends
+		_, _ = w.Write([]byte(`{"id":2,"name":"updated"}` + "\n" + `{"id":3,"name":"deleted"}`))
+	}))
+	defer srv.Close()
+
+	var events []streamEvent
+	err := New[streamEvent]().SetRequest(MethodGet, srv.URL).StreamJSON(func(e streamEvent) error {
+		if events = append(events, e); len(events) == 1 {
+			close(got)
+		}
+		return nil
+	})
+	if err != nil || len(events) != 3 || events[2].Name != "deleted" {
+		t.Fatalf("events = %+v, err = %v", events, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = New[streamEvent]().SetRequest(MethodGet, newTestServer(t, http.StatusOK, "{\"id\":1}\n{\"id\":2}\n").URL).
+		StreamJSON(func(e streamEvent) error {
+			calls++
+			return stop
+		})
+	if err != stop || calls != 1 {
+		t.Errorf("err = %v, calls = %d, want the callback error after one event", err, calls)
+	}
+
+	c := New[streamEvent]().SetRequest(MethodGet, newTestServer(t, http.StatusOK, "{\"id\":1}\nnot json\n").URL)
+	if err = c.StreamJSON(func(e streamEvent) error { return nil }); err == nil || c.Exception.Kind != KindDecode {
+		t.Errorf("err = %v, exception = %+v, want a decode error", err, c.Exception)
+	}
+
+	c = New[streamEvent]().SetRequest(MethodGet, newTestServer(t, http.StatusBadGateway, "bad gateway").URL)
+	err = c.StreamJSON(func(e streamEvent) error {
+		t.Error("callback is called for a failed status")
+		return nil
+	})
+	if c.Exception.Kind != KindStatus || c.Exception.StatusError.StatusCode != http.StatusBadGateway || err == nil {
+		t.Errorf("err = %v, exception = %+v, want a 502 status error", err, c.Exception)
+	}
+}
+
+func TestStreamSSE(t *testing.T) {
+	stream := ": keep-alive\n\n" +
+		"data: hello\n\n" +
+		"event: update\r\nid: 7\r\ndata: line 1\r\ndata:line 2\r\n\r\n" +
+		"event: ignored\n\n" +
+		"data: incomplete"
+	srv := newTestServer(t, http.StatusOK, stream)
+
+	var got []string
+	err := New[any]().SetRequest(MethodGet, srv.URL).StreamSSE(func(event, data string) error {
+		got = append(got, event+"="+data)
+		return nil
+	})
+	if err != nil || len(got) != 2 || got[0] != "message=hello" || got[1] != "update=line 1\nline 2" {
+		t.Fatalf("events = %q, err = %v", got, err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// RequestTemplate defines an endpoint as data, whose variables are bound later with Bind.
+//
+// The Path may hold ":name" placeholders, filled like SetPathParams. A Query value of the form ":name"
+// is replaced by the variable of the same name, and the Body is a text/template which is executed with
+// the variables, such as `{"name": {{json .name}}}`, where the json function encodes a value as json.
+type RequestTemplate struct {
+	Method  string // request method, such as MethodPost
+	Path    string // request path, such as "/orgs/:org/members"
+	Query   SMap   // default query parameters, a ":name" value is bound to a variable
+	Headers H      // default request headers
+	Body    string // body template, optional
+}
+
+// requestTemplate is a RequestTemplate prepared by SetRequestTemplate.
+type requestTemplate struct {
+	RequestTemplate
+	body *template.Template
+}
+
+// templateFuncs are the functions available in a body template.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// SetRequestTemplate sets the request of the client instance from a template.
+// It takes a `tmpl` parameter, which defines the method, the path, the query parameters, the headers and
+// the body template of an endpoint. The variables of the template are bound with the Bind method, so that
+// teams can store their endpoint definitions as data.
+// An invalid body template is recorded as an Exception.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+//
+// Example usage:
+//
+//	tmpl := gloria.RequestTemplate{
+//		Method: gloria.MethodPost,
+//		Path:   "/orgs/:org/members",
+//		Body:   `{"name": {{json .name}}}`,
+//	}
+//	client.SetRequestTemplate(tmpl).Bind(gloria.SMap{"org": "acme", "name": "mystic"}).Send()
+func (c *Client[T]) SetRequestTemplate(tmpl RequestTemplate) *Client[T] {
+	c.template = &requestTemplate{RequestTemplate: tmpl}
+
+	if !isEmptyString(tmpl.Body) {
+		body, err := template.New(tmpl.Path).Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl.Body)
+		if err != nil {
+			c.failBuild(&Exception{
+				CodeLocation:   fileLocation(1),
+				Kind:           KindRequest,
+				PanicError:     fmt.Errorf("parse body template: %w", err),
+				OccurrenceTime: time.Now().Unix(),
+			})
+			return c
+		}
+		c.template.body = body
+	}
+
+	c.SetRequest(tmpl.Method, tmpl.Path)
+	if !isEmpty(tmpl.Headers) {
+		c.SetHeaders(tmpl.Headers)
+	}
+
+	return c
+}
+
+// Bind binds the variables of the template set by SetRequestTemplate.
+// It takes a `vars` parameter, whose values fill the ":name" placeholders of the path (url-escaped) and
+// of the query parameters, and render the body template.
+// A body template which cannot be rendered (such as a missing variable) is recorded as an Exception.
+// It returns a pointer to the `Client` instance to allow for method chaining.
+func (c *Client[T]) Bind(vars SMap) *Client[T] {
+	if c.template == nil {
+		return c
+	}
+
+	c.SetPathParams(vars)
+
+	for key, value := range c.template.Query {
+		if name := strings.TrimPrefix(value, signColon); name != value {
+			value = vars[name]
+		}
+		c.SetQueryParam(key, value)
+	}
+
+	if c.template.body != nil {
+		var buf bytes.Buffer
+		if err := c.template.body.Execute(&buf, vars); err != nil {
+			c.failBuild(&Exception{
+				CodeLocation:   fileLocation(1),
+				Kind:           KindRequest,
+				PanicError:     fmt.Errorf("render body template: %w", err),
+				OccurrenceTime: time.Now().Unix(),
+			})
+			return c
+		}
+		c.SetBodyReader(bytes.NewReader(buf.Bytes()), "", int64(buf.Len()))
+	}
+
+	return c
+}
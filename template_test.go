@@ -0,0 +1,46 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetRequestTemplate(t *testing.T) {
+	var gotPath, gotQuery, gotBody, gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotPath, gotQuery, gotBody, gotHeader = r.URL.EscapedPath(), r.URL.RawQuery, string(b), r.Header.Get("X-Source")
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	tmpl := RequestTemplate{
+		Method:  MethodPost,
+		Path:    srv.URL + "/orgs/:org/members",
+		Query:   SMap{"role": ":role", "notify": "true"},
+		Headers: H{"X-Source": "template"},
+		Body:    `{"name":{{json .name}}}`,
+	}
+
+	c := New[H]().SetRequestTemplate(tmpl).Bind(SMap{"org": "acme corp", "role": "admin", "name": `Jo "J"`}).Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if gotPath != "/orgs/acme%20corp/members" || gotQuery != "notify=true&role=admin" || gotHeader != "template" {
+		t.Errorf("path = %q, query = %q, header = %q", gotPath, gotQuery, gotHeader)
+	}
+	if gotBody != `{"name":"Jo \"J\""}` {
+		t.Errorf("body = %s", gotBody)
+	}
+
+	c = New[H]().SetRequestTemplate(tmpl).Bind(SMap{"org": "acme"})
+	if c.Exception.Kind != KindRequest {
+		t.Errorf("exception = %+v, want a request error for the missing variable", c.Exception)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// traceTimings records the connection of the attempts of a request, and their phase durations if
+// the client trace is enabled (see WithClientTrace). The records of the last attempt are kept.
+// The hooks of an attempt may run concurrently, such as the dials of both address families, hence
+// the mutex.
+type traceTimings struct {
+	mu sync.Mutex
+
+	start        time.Time // start of the attempt
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+
+	dns     time.Duration
+	connect time.Duration
+	tls     time.Duration
+	ttfb    time.Duration
+
+	reused bool // whether the connection was reused
+}
+
+// clientTrace returns the hooks recording the connection into t, and the phase durations if phases is set.
+func (t *traceTimings) clientTrace(phases bool) *httptrace.ClientTrace {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+	}
+	if !phases {
+		return trace
+	}
+
+	trace.GetConn = func(string) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		// a new attempt, a reused connection has no dns, connect or tls phase
+		t.start, t.dnsStart, t.connectStart, t.tlsStart = time.Now(), time.Time{}, time.Time{}, time.Time{}
+		t.dns, t.connect, t.tls, t.ttfb = 0, 0, 0, 0
+	}
+	trace.DNSStart = func(httptrace.DNSStartInfo) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.dnsStart = time.Now()
+	}
+	trace.DNSDone = func(httptrace.DNSDoneInfo) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.dns = time.Since(t.dnsStart)
+	}
+	trace.ConnectStart = func(string, string) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.connectStart.IsZero() {
+			t.connectStart = time.Now()
+		}
+	}
+	trace.ConnectDone = func(_, _ string, err error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if err == nil {
+			t.connect = time.Since(t.connectStart)
+		}
+	}
+	trace.TLSHandshakeStart = func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.tlsStart = time.Now()
+	}
+	trace.TLSHandshakeDone = func(tls.ConnectionState, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.tls = time.Since(t.tlsStart)
+	}
+	trace.GotFirstResponseByte = func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.ttfb = time.Since(t.start)
+	}
+
+	return trace
+}
+
+// record copies the records of the last attempt into meta.
+func (t *traceTimings) record(meta *Meta) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	meta.DNSTime, meta.ConnectTime, meta.TLSTime, meta.TTFB = t.dns, t.connect, t.tls, t.ttfb
+	meta.ConnReused = t.reused
+}
+
+// ConnectionReused reports whether the last attempt of the completed request was sent on a reused
+// (keep-alive) connection, to verify the connection pooling, such as in a load test.
+func (c *Client[T]) ConnectionReused() bool {
+	return c.Meta.ConnReused
+}
+
+// EchoTrace returns a summary of the phase durations of the completed request, recorded when the
+// client trace is enabled (see WithClientTrace), and whether its connection was reused, such as
+// "DNS 1.2ms, Connect 350µs, TLS 4.1ms, TTFB 12ms, Reused false".
+// The phases of a reused connection are zero. It returns "-" if the client trace is disabled.
+func (c *Client[T]) EchoTrace() string {
+	if !c.Config.ClientTrace {
+		return "-"
+	}
+	return fmt.Sprintf("DNS %v, Connect %v, TLS %v, TTFB %v, Reused %t",
+		c.Meta.DNSTime, c.Meta.ConnectTime, c.Meta.TLSTime, c.Meta.TTFB, c.Meta.ConnReused)
+}
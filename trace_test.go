@@ -0,0 +1,46 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSend_ClientTrace(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	c := New[H]().Optional(WithSkipTLS[H](true), WithClientTrace[H](true)).SetRequest(MethodGet, srv.URL).Send()
+	if !isEmpty(c.Exception) {
+		t.Fatalf("unexpected exception: %+v", c.Exception)
+	}
+	if c.Meta.ConnectTime <= 0 || c.Meta.TLSTime <= 0 || c.Meta.TTFB <= 0 {
+		t.Errorf("connect = %v, tls = %v, ttfb = %v, want positive durations", c.Meta.ConnectTime, c.Meta.TLSTime, c.Meta.TTFB)
+	}
+	if trace := c.EchoTrace(); !strings.HasPrefix(trace, "DNS ") || !strings.Contains(trace, "TTFB ") {
+		t.Errorf("EchoTrace() = %q", trace)
+	}
+
+	if c.ConnectionReused() || !strings.HasSuffix(c.EchoTrace(), "Reused false") {
+		t.Errorf("reused = %t, trace = %q, want a new connection", c.ConnectionReused(), c.EchoTrace())
+	}
+	if c.SetRequest(MethodGet, srv.URL).Send(); !c.ConnectionReused() || c.Meta.ConnectTime != 0 {
+		t.Errorf("reused = %t, connect = %v, want the connection reused", c.ConnectionReused(), c.Meta.ConnectTime)
+	}
+
+	if c.Reset(); c.Meta.TTFB != 0 {
+		t.Errorf("ttfb = %v after Reset, want 0", c.Meta.TTFB)
+	}
+
+	c = New[H]().Optional(WithSkipTLS[H](true)).SetRequest(MethodGet, srv.URL).Send()
+	if c.Meta.TTFB != 0 || c.EchoTrace() != "-" {
+		t.Errorf("ttfb = %v, trace = %q, want no trace when disabled", c.Meta.TTFB, c.EchoTrace())
+	}
+}
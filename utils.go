@@ -5,17 +5,32 @@
 package gloria
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"reflect"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // isEmptyString checks if a string is empty or equals to a "-" value.
@@ -25,11 +40,12 @@ func isEmptyString(s string) bool {
 	return s == "" || s == signHorizontal
 }
 
-// containsMethod checks if a string is present in the list of query methods.
+// containsMethod checks if a string is present in the list of query methods, including the custom
+// methods of RegisterMethod.
 // The 's' parameter is the string to be checked.
 // It returns true if the string is found in the list, false otherwise.
 func containsMethod(s string) bool {
-	for _, str := range QueryMethods {
+	for _, str := range registeredMethods() {
 		if s == str {
 			return true
 		}
@@ -42,10 +58,92 @@ func containsMethod(s string) bool {
 // If the string is not a valid query method, it throws a panic with an error message.
 func isValidMethod(s string) {
 	if !containsMethod(s) {
-		panic(fmt.Errorf(`Must choose one of "%s"`, strings.Join(QueryMethods, ", ")))
+		panic(fmt.Errorf(`Must choose one of "%s", or register the method with RegisterMethod`,
+			strings.Join(registeredMethods(), ", ")))
 	}
 }
 
+// isToken checks if a string is a valid http token (RFC 7230), such as a request method.
+func isToken(s string) bool {
+	if isEmptyString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// isAcceptStatus checks if an http status code is accepted as a success.
+// The 'code' parameter is the status code to be checked, and 'accepted' is the list of accepted codes.
+// It returns true if the code is in the list, or if the list is empty and the code is a 2xx status.
+func isAcceptStatus(code int, accepted []int) bool {
+	if len(accepted) == 0 {
+		return code >= http.StatusOK && code < http.StatusMultipleChoices
+	}
+	for _, v := range accepted {
+		if code == v {
+			return true
+		}
+	}
+	return false
+}
+
+// isOkCode checks if the business code is a success code.
+// The 'okCodes' parameter is the set of the success codes, only 'defaultCode' is a success code if it is empty.
+func isOkCode(code, defaultCode int, okCodes []int) bool {
+	if len(okCodes) == 0 {
+		return code == defaultCode
+	}
+	for _, v := range okCodes {
+		if code == v {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryable checks if the result of a request attempt is worth retrying.
+// The 'resp' and 'err' parameters are the result of the attempt.
+// It returns true for network errors (except the cancellation of the request context), and for
+// the 429 Too Many Requests and 5xx statuses, and false otherwise.
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrRedirectLoop) && !errors.Is(err, ErrTooManyRedirects)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter returns the wait duration requested by the Retry-After header of a 429 Too Many Requests
+// or 503 Service Unavailable response, in seconds or as an http date relative to now.
+// It returns false if the response has no such header, or if the header is malformed.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if isEmpty(value) {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
 // isValidHost checks if a string is a valid host.
 // The 'host' parameter is the string to be checked.
 // It returns true if the host is valid, and false otherwise.
@@ -57,20 +155,31 @@ func isValidHost(host string) bool {
 }
 
 // isValidIPAddrPort checks if a string is a valid IP address and port combination.
-// The 'ipAddrPort' parameter is the string to be checked.
+// The 'ipAddrPort' parameter is the string to be checked, the port is optional and IPv6
+// addresses are supported in both the bare ("::1") and bracketed ("[::1]:8080") forms.
 // It returns true if the IP address and port combination is valid, and false otherwise.
 func isValidIPAddrPort(ipAddrPort string) bool {
-	switch parts := strings.Split(ipAddrPort, ":"); len(parts) {
-	case 2:
-		if port, err := strconv.Atoi(parts[1]); err != nil || port < 0 || port > 65535 {
-			return false
+	host, port, err := net.SplitHostPort(ipAddrPort)
+	if err != nil {
+		// Without a port, such as "10.0.0.1", "::1" or "[::1]"
+		host = ipAddrPort
+		if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+			host = host[1 : len(host)-1]
 		}
-		fallthrough
-	case 1:
-		return net.ParseIP(parts[0]) != nil
-	default:
+		return net.ParseIP(host) != nil
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
 		return false
 	}
+	return net.ParseIP(host) != nil
+}
+
+// isBareIPv6 checks if a string is an IPv6 address without brackets and port, such as "::1".
+// The 'host' parameter is the string to be checked.
+// It returns true if the host must be bracketed to be used in a URL, and false otherwise.
+func isBareIPv6(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.To4() == nil
 }
 
 // isEmpty checks if a value is empty.
@@ -89,22 +198,76 @@ func isEmpty(value interface{}) bool {
 		return len(v) == 0
 	case []string:
 		return len(v) == 0
+	case *Exception:
+		// before the error case, since an Exception is an error
+		if v == nil || (v.CodeLocation == "" && v.PanicError == nil && v.FailureReason == "" && v.StatusError == nil && v.OccurrenceTime == 0) {
+			return true
+		}
+		return false
 	case error:
 		return v == nil
 	case []*http.Cookie:
 		return len(v) == 0
 	case *log.Logger:
 		return v == nil
-	case *Exception:
-		if v == nil || (v.CodeLocation == "" && v.PanicError == nil && v.FailureReason == "" && v.OccurrenceTime == 0) {
-			return true
-		}
-		return false
 	default:
 		return reflect.DeepEqual(value, reflect.Zero(reflect.TypeOf(value)).Interface())
 	}
 }
 
+// isNil checks if a value is nil, including a nil pointer, map, slice, channel, function or interface
+// wrapped in the interface.
+// The 'value' parameter is the value to be checked.
+// It returns true if the value is nil, and false otherwise, even for a zero value like an empty struct.
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// progressReader is a request body which reports the bytes read to a progress callback.
+type progressReader struct {
+	io.ReadCloser
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+// newProgressReader wraps the request body rc of the total length to report its progress.
+func newProgressReader(rc io.ReadCloser, total int64, progress func(sent, total int64)) *progressReader {
+	return &progressReader{ReadCloser: rc, total: total, progress: progress}
+}
+
+// Read implements the io.Reader interface, it reports the bytes read so far after each read.
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
+
+// isTerminal checks if a writer is a terminal device, such as os.Stdout attached to a TTY.
+// The 'w' parameter is the writer to be checked.
+// It returns true if the writer is a character device, and false otherwise (files, pipes, buffers).
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 // urlSegments parses the URL path and returns a rawUrl struct.
 // The 'urlpath' parameter is the URL path to be parsed.
 // It returns a pointer to the rawUrl struct.
@@ -116,7 +279,8 @@ func urlSegments(urlpath string) (seg *rawUrl) {
 
 	scheme := parsedURL.Scheme
 	host := parsedURL.Host
-	path := parsedURL.Path
+	// keep the escaped form, so that escaped path parameters (such as "%2F") are not decoded
+	path := parsedURL.EscapedPath()
 
 	// parse query parameters
 	queryParams := parsedURL.Query()
@@ -149,6 +313,63 @@ func urlSegments(urlpath string) (seg *rawUrl) {
 	return
 }
 
+// replacePathParams replaces the ":name" placeholders of a path positionally with url-escaped values.
+// The 'path' parameter is the path template, and 'values' are the values to fill in from left to right.
+// It returns the path with the placeholders replaced.
+func replacePathParams(path string, values []string) string {
+	if len(values) == 0 {
+		return path
+	}
+
+	segments := strings.Split(path, signSlash)
+	next := 0
+	for i, seg := range segments {
+		if next == len(values) {
+			break
+		}
+		if len(seg) > 1 && strings.HasPrefix(seg, signColon) {
+			segments[i] = url.PathEscape(values[next])
+			next++
+		}
+	}
+	return strings.Join(segments, signSlash)
+}
+
+// insertAPIVersion injects the version segment into the path of the url urlPath, whose scheme and host
+// are origin: it replaces the ":version" placeholder if any, otherwise it prefixes the path.
+func insertAPIVersion(urlPath, origin, version string) string {
+	path := strings.TrimPrefix(urlPath, origin)
+	segments := strings.Split(path, signSlash)
+	for i, seg := range segments {
+		if seg == signColon+"version" {
+			segments[i] = url.PathEscape(version)
+			return origin + strings.Join(segments, signSlash)
+		}
+	}
+	if path == RootURL {
+		path = ""
+	}
+	return origin + signSlash + url.PathEscape(version) + path
+}
+
+// fillPathParams replaces the ":name" placeholders of a path with the url-escaped named values.
+// The 'path' parameter is the path template, and 'params' are the values keyed by placeholder name.
+// It returns an error if a placeholder is left without a value.
+func fillPathParams(path string, params SMap) (string, error) {
+	segments := strings.Split(path, signSlash)
+	for i, seg := range segments {
+		if len(seg) <= 1 || !strings.HasPrefix(seg, signColon) {
+			continue
+		}
+		value, ok := params[seg[1:]]
+		if !ok {
+			return "", fmt.Errorf("path parameter %q of %q is not filled", seg, path)
+		}
+		segments[i] = url.PathEscape(value)
+	}
+	return strings.Join(segments, signSlash), nil
+}
+
 // convertToSMap converts a map of values to a string map.
 // The 'input' parameter is the input map to be converted.
 // It returns the converted string map.
@@ -179,6 +400,121 @@ func convertToSMap(input H) SMap {
 	return output
 }
 
+// structToQuery converts the fields of a struct to query parameters, see SetQueryStruct.
+// The values are normalized to the types supported by convertToSMap.
+func structToQuery(v any) (H, error) {
+	return structToParams(v, "query")
+}
+
+// structToForm converts the fields of a struct to form values, see SetFormStruct.
+// Unlike the query parameters, the values of a slice are repeated under the same key.
+func structToForm(v any) (url.Values, error) {
+	params, err := structToParams(v, "form")
+	if err != nil {
+		return nil, err
+	}
+
+	form := make(url.Values, len(params))
+	for k, pv := range params {
+		if vs, ok := pv.([]string); ok {
+			form[k] = vs
+			continue
+		}
+		form.Set(k, convertToSMap(H{k: pv})[k])
+	}
+	return form, nil
+}
+
+// structToParams converts the fields of a struct named by the tag key to parameters.
+func structToParams(v any, key string) (H, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s struct: %T is not a struct", key, v)
+	}
+
+	params := H{}
+	structValueToParams(rv, key, params)
+
+	return params, nil
+}
+
+// structValueToParams adds the fields of the struct value rv named by the tag key to params.
+// The value is walked through reflection only, since the fields of an unexported embedded struct
+// cannot be converted to an interface.
+func structValueToParams(rv reflect.Value, key string, params H) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field, value := rt.Field(i), rv.Field(i)
+		tag := field.Tag.Get(key)
+		if tag == signHorizontal || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		// Promote the fields of an embedded struct
+		if field.Anonymous && isEmpty(tag) {
+			for value.Kind() == reflect.Pointer && !value.IsNil() {
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				structValueToParams(value, key, params)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if isEmpty(name) {
+			name = field.Name
+		}
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		if opts == "omitempty" && value.IsZero() {
+			continue
+		}
+		params[name] = queryValue(value)
+	}
+}
+
+// queryValue normalizes a value to a type supported by convertToSMap.
+func queryValue(v reflect.Value) any {
+	// the fields of an unexported embedded struct cannot be converted to an interface
+	if v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Slice, reflect.Array:
+		vs := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			vs = append(vs, convertToSMap(H{"": queryValue(v.Index(i))})[""])
+		}
+		return vs
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 // getUserAgent generates the User-Agent string for the HTTP request.
 // It combines the application name, version, operating system, architecture, and Go version.
 // It returns the User-Agent string.
@@ -194,6 +530,18 @@ func getUserAgent() string {
 	return ua
 }
 
+// newUUID generates a random (version 4) UUID string, such as "3de4fd33-f18b-4e2a-906b-7927f6e5828f".
+// It panics if the system random source fails, which is not expected to happen.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Errorf("generate uuid error: %w", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
 // getBearerAuth generates the Bearer authentication header value.
 // The 'token' parameter is the token to be included in the header.
 // It returns the Bearer authentication header value.
@@ -209,3 +557,104 @@ func getBasicAuth(username, password string) string {
 	credentials := base64.StdEncoding.EncodeToString([]byte(auth))
 	return fmt.Sprintf("%s %s", AuthTypeBasic, credentials)
 }
+
+// compressBody compresses data with the given content encoding, gzip or deflate.
+func compressBody(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionDeflate:
+		// HTTP "deflate" is the zlib format (RFC 9110), not a raw deflate stream
+		w = zlib.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unsupported request compression %q", encoding)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// matchContentType reports whether a Content-Type matches the expected one, by prefix and ignoring the
+// parameters (such as the charset) and the case, that is "application/json; charset=utf-8" matches
+// "application/json". Any Content-Type matches an empty expected one.
+func matchContentType(contentType, expected string) bool {
+	if isEmptyString(expected) {
+		return true
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	want, _, _ := strings.Cut(expected, ";")
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(mediaType)), strings.ToLower(strings.TrimSpace(want)))
+}
+
+// isStructuredContentType reports whether a Content-Type denotes a decodable (json or xml) body,
+// such as "application/json" or "application/problem+xml".
+// An empty or malformed Content-Type is considered as structured, so that the body is decoded.
+func isStructuredContentType(contentType string) bool {
+	if isEmptyString(contentType) {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+
+	switch {
+	case mediaType == JsonContentType, mediaType == "application/xml", mediaType == "text/xml":
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	default:
+		return false
+	}
+}
+
+// tlsVersionName returns the name of a TLS version, such as "TLS 1.3".
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
+
+// verifyCertPins returns a tls.Config.VerifyPeerCertificate function which accepts only the leaf
+// certificates whose SHA-256 fingerprint is in pins. It returns nil if pins is empty.
+func verifyCertPins(pins []string) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(pins) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		allowed[strings.ToLower(strings.ReplaceAll(pin, signColon, ""))] = true
+	}
+
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return ErrCertNotPinned
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		fingerprint := hex.EncodeToString(sum[:])
+		if !allowed[fingerprint] {
+			return fmt.Errorf("%w: sha256 fingerprint %s", ErrCertNotPinned, fingerprint)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReplacePathParams(t *testing.T) {
+	tests := []struct {
+		path   string
+		values []string
+		want   string
+	}{
+		{"https://example.org/users/:id", nil, "https://example.org/users/:id"},
+		{"https://example.org/users/:id", []string{"123"}, "https://example.org/users/123"},
+		{"https://example.org:8080/users/:id/:sid", []string{"123", "456"}, "https://example.org:8080/users/123/456"},
+		{"/orgs/:org/teams/:team/members/:user", []string{"acme", "core", "mystic"}, "/orgs/acme/teams/core/members/mystic"},
+		{"/orgs/:org/teams/:team", []string{"acme"}, "/orgs/acme/teams/:team"},
+		{"/users/:id", []string{"1", "2"}, "/users/1"},
+		{"/users/:id/:sid", []string{"a/b", "hello world"}, "/users/a%2Fb/hello%20world"},
+		{"/users/:id", []string{"../admin"}, "/users/..%2Fadmin"},
+	}
+
+	for _, tt := range tests {
+		if got := replacePathParams(tt.path, tt.values); got != tt.want {
+			t.Errorf("replacePathParams(%q, %v) = %q, want %q", tt.path, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestFillPathParams(t *testing.T) {
+	got, err := fillPathParams("https://example.org/orgs/:org/teams/:team", SMap{"team": "core", "org": "acme"})
+	if err != nil || got != "https://example.org/orgs/acme/teams/core" {
+		t.Errorf("fillPathParams = %q, %v", got, err)
+	}
+
+	if _, err = fillPathParams("https://example.org/orgs/:org/teams/:team", SMap{"org": "acme"}); err == nil {
+		t.Error("an unfilled placeholder should be an error")
+	}
+}
+
+func TestIsValidIPAddrPort(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"10.0.0.1", true},
+		{"10.0.0.1:8080", true},
+		{"10.0.0.1:70000", false},
+		{"::1", true},
+		{"[::1]", true},
+		{"[::1]:8080", true},
+		{"[2001:db8::1]:443", true},
+		{"2001:db8::1", true},
+		{"[2001:db8::1]:port", false},
+		{"example.org", false},
+	}
+
+	for _, tt := range tests {
+		if got := isValidIPAddrPort(tt.host); got != tt.want {
+			t.Errorf("isValidIPAddrPort(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		status int
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{http.StatusTooManyRequests, "3", 3 * time.Second, true},
+		{http.StatusServiceUnavailable, now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{http.StatusServiceUnavailable, now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{http.StatusTooManyRequests, "", 0, false},
+		{http.StatusTooManyRequests, "-1", 0, false},
+		{http.StatusTooManyRequests, "soon", 0, false},
+		{http.StatusBadGateway, "3", 0, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		if got, ok := retryAfter(resp, now); got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%d, %q) = %v, %v, want %v, %v", tt.status, tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -9,7 +9,7 @@ var (
 	Title string = "Gloria"
 
 	// Version is the version number of the application.
-	Version string = "1.0.0"
+	Version string = "1.1.0"
 
 	// BuildTime is the build time of the application.
 	// Better practice: go build -ldflags "-X main.BuildTime=$(date '+%Y-%m-%d %H:%M:%S')" main.go
@@ -0,0 +1,10 @@
+module github.com/pokeyaro/gloria/websocket
+
+go 1.20
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/pokeyaro/gloria v1.1.0
+)
+
+require github.com/goccy/go-json v0.10.2 // indirect
@@ -0,0 +1,113 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package websocket opens websocket connections with the url, the headers, the authorization and the
+// tls configuration of a gloria client, in a separate module so that gloria does not force the
+// gorilla/websocket dependency.
+//
+// Example usage:
+//
+//	c := gloria.New[any]().SetRequest(gloria.MethodGet, "https://example.com/ws").SetBearerAuth(token)
+//	conn, err := websocket.Upgrade(c)
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+
+	"github.com/pokeyaro/gloria"
+)
+
+// handshakeHeaders are the request headers set by the websocket handshake itself.
+var handshakeHeaders = []string{
+	"Upgrade",
+	"Connection",
+	"Sec-Websocket-Key",
+	"Sec-Websocket-Version",
+	"Sec-Websocket-Extensions",
+}
+
+// Upgrade performs the websocket handshake with the request of the client instance, the http or https
+// scheme of the url is replaced by ws or wss, and the connection uses the tls configuration and the
+// timeout of the client instance. A response other than 101 Switching Protocols is recorded as a
+// KindStatus Exception, and its StatusError is returned.
+func Upgrade[T any](c *gloria.Client[T]) (*ws.Conn, error) {
+	req, err := c.BuildRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	u := *req.URL
+	switch u.Scheme {
+	case gloria.ProtocolHttp:
+		u.Scheme = "ws"
+	case gloria.ProtocolHttps:
+		u.Scheme = "wss"
+	}
+
+	header := req.Header.Clone()
+	for _, k := range handshakeHeaders {
+		header.Del(k)
+	}
+
+	dialer := &ws.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		TLSClientConfig:  c.TLSConfig(),
+		HandshakeTimeout: c.Config.Timeout,
+	}
+
+	conn, resp, err := dialer.DialContext(req.Context(), u.String(), header)
+	if err == nil {
+		return conn, nil
+	}
+
+	if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+		// the dialer keeps the beginning of the body of a failed handshake
+		body, _ := ioutil.ReadAll(resp.Body)
+		statusErr := &gloria.StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+		c.Exception = &gloria.Exception{
+			CodeLocation:   fileLocation(),
+			Kind:           gloria.KindStatus,
+			FailureReason:  statusErr.Error(),
+			StatusError:    statusErr,
+			OccurrenceTime: time.Now().Unix(),
+		}
+		return nil, statusErr
+	}
+
+	c.Exception = &gloria.Exception{
+		CodeLocation:   fileLocation(),
+		Kind:           classifyError(err),
+		PanicError:     err,
+		OccurrenceTime: time.Now().Unix(),
+	}
+	return nil, err
+}
+
+// classifyError returns the kind of a dial error, like the transport errors of gloria.
+func classifyError(err error) gloria.ErrorKind {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return gloria.KindTimeout
+	}
+	return gloria.KindNetwork
+}
+
+// fileLocation returns the file name and the line number of its caller, like the one of gloria.
+func fileLocation() string {
+	_, file, line, ok := runtime.Caller(1)
+	if !ok {
+		file, line = "???", 0
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+}
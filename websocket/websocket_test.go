@@ -0,0 +1,54 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ws "github.com/gorilla/websocket"
+
+	"github.com/pokeyaro/gloria"
+)
+
+func TestUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := (&ws.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mt, msg, err := conn.ReadMessage()
+		if err == nil {
+			_ = conn.WriteMessage(mt, msg)
+		}
+	}))
+	defer srv.Close()
+
+	c := gloria.New[any]().SetRequest(gloria.MethodGet, srv.URL+"/ws").SetBearerAuth("secret")
+	conn, err := Upgrade(c)
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	defer conn.Close()
+
+	if err = conn.WriteMessage(ws.TextMessage, []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "ping" {
+		t.Errorf("ReadMessage() = %q, %v, want the echo", msg, err)
+	}
+
+	c = gloria.New[any]().SetRequest(gloria.MethodGet, srv.URL+"/ws")
+	if _, err = Upgrade(c); err == nil || c.Exception.Kind != gloria.KindStatus ||
+		c.Exception.StatusError.StatusCode != http.StatusUnauthorized {
+		t.Errorf("err = %v, exception = %+v, want a 401 status error", err, c.Exception)
+	}
+}
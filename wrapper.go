@@ -10,18 +10,27 @@ import (
 )
 
 // DecoratorTimer a decorator for timing functions to test api performance.
+// It prints the duration and panics on error, see MeasureTimer for a composable variant.
 func DecoratorTimer(fn func() error) {
-	// Start
-	startTime := time.Now()
-
 	// Execute
-	if e := fn(); e != nil {
+	duration, e := MeasureTimer(fn)
+	if e != nil {
 		panic(e.Error())
 	}
 
-	// Finish
-	duration := time.Since(startTime)
-
 	// Output
 	fmt.Printf("api request duration: %v\n", duration)
 }
+
+// MeasureTimer times the execution of a function to test api performance.
+// It returns the elapsed time and the error of the function, without printing or panicking.
+func MeasureTimer(fn func() error) (time.Duration, error) {
+	// Start
+	startTime := time.Now()
+
+	// Execute
+	err := fn()
+
+	// Finish
+	return time.Since(startTime), err
+}
@@ -0,0 +1,26 @@
+// Copyright (c) 2023 Pokeya Boa <pokeya.mystic@gmail.com>, All rights reserved.
+// resty source code and usage is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package gloria
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMeasureTimer(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	d, err := MeasureTimer(func() error {
+		time.Sleep(10 * time.Millisecond)
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Errorf("err = %v, want %v", err, errBoom)
+	}
+	if d < 10*time.Millisecond {
+		t.Errorf("duration = %s, want at least 10ms", d)
+	}
+}